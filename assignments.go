@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// Fields of an assignment the user can set themselves, see the assignment_overrides
+// table. Each one set wins over Canvas (or the AI estimates) until it's set to null.
+var overrideFields = []string{"name", "due_date", "difficulty", "length"}
+
+// How many times a write without If-Match is retried when the assignment changes
+// underneath it, e.g. during a sync
+const maxUpdateAttempts = 3
+
+// Error for a request body or query string that can't be applied, reported as a 400
+type invalidFieldError struct {
+	field   string
+	message string
+}
+
+func (e invalidFieldError) Error() string {
+	return fmt.Sprintf("%s %s", e.field, e.message)
+}
+
+// Values of the fields a request sets, the invalid ones are cleared
+type assignmentFields struct {
+	Name        sql.NullString
+	CourseID    sql.NullInt64
+	DueDate     sql.NullTime
+	Difficulty  sql.NullInt64
+	Length      sql.NullInt64
+	Description sql.NullString
+}
+
+// The fields a request sets or clears. Fields missing from set are left as they are.
+type fieldPatch struct {
+	set    map[string]bool
+	values assignmentFields
+}
+
+func (p fieldPatch) applyOverride(o sqlite.AssignmentOverride) sqlite.AssignmentOverride {
+	if p.set["name"] {
+		o.Name = p.values.Name
+	}
+	if p.set["due_date"] {
+		o.DueDate = p.values.DueDate
+	}
+	if p.set["difficulty"] {
+		o.Difficulty = p.values.Difficulty
+	}
+	if p.set["length"] {
+		o.Length = p.values.Length
+	}
+	return o
+}
+
+// Reads the allowed fields from a JSON object. With replace every field is set, the
+// ones missing from the body are cleared; otherwise it's a JSON merge patch (RFC 7396)
+// where only the fields in the body change and null clears them.
+func parseFields(body []byte, allowed []string, replace bool) (fieldPatch, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return fieldPatch{}, errors.New("body must be a JSON object")
+	}
+
+	patch := fieldPatch{set: make(map[string]bool)}
+	for name := range fields {
+		if !slices.Contains(allowed, name) {
+			return patch, invalidFieldError{name, "can't be changed"}
+		}
+	}
+	for _, name := range allowed {
+		raw, ok := fields[name]
+		if !ok && !replace {
+			continue
+		}
+		patch.set[name] = true
+		if !ok || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		if err := parseField(&patch.values, name, raw); err != nil {
+			return patch, err
+		}
+	}
+	return patch, nil
+}
+
+func parseField(o *assignmentFields, name string, raw json.RawMessage) error {
+	switch name {
+	case "name":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return invalidFieldError{name, "must be a string"}
+		}
+		s = strings.TrimSpace(s)
+		// The Postgres column is a VARCHAR(255)
+		if s == "" || utf8.RuneCountInString(s) > 255 {
+			return invalidFieldError{name, "must be between 1 and 255 characters"}
+		}
+		o.Name = sql.NullString{String: s, Valid: true}
+	case "due_date":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return invalidFieldError{name, "must be an RFC3339 date, e.g. 2025-05-01T23:59:00Z"}
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return invalidFieldError{name, "must be an RFC3339 date, e.g. 2025-05-01T23:59:00Z"}
+		}
+		o.DueDate = sql.NullTime{Time: t.UTC(), Valid: true}
+	case "difficulty":
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil || n < 1 || n > 10 {
+			return invalidFieldError{name, "must be an integer from 1 to 10"}
+		}
+		o.Difficulty = sql.NullInt64{Int64: n, Valid: true}
+	case "length":
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil || n < 0 {
+			return invalidFieldError{name, "must be a non-negative number of minutes"}
+		}
+		o.Length = sql.NullInt64{Int64: n, Valid: true}
+	case "course_id":
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil || n < 0 {
+			return invalidFieldError{name, "must be a course ID"}
+		}
+		o.CourseID = sql.NullInt64{Int64: n, Valid: true}
+	case "description":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return invalidFieldError{name, "must be a string"}
+		}
+		o.Description = sql.NullString{String: s, Valid: s != ""}
+	}
+	return nil
+}
+
+// ETag of an assignment, it changes whenever the assignment is written to
+func assignmentETag(a sqlite.Assignment) string {
+	return fmt.Sprintf(`"%d"`, a.Version)
+}
+
+// Whether an If-Match header matches the ETag, weak tags never do
+func etagMatches(ifMatch, etag string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the user's overrides of an assignment, fields left out of the body go back
+// to Canvas's values
+func UpdateAssignment(c *gin.Context, q store.Store) {
+	saveOverrides(c, q, true)
+}
+
+// Changes some of the user's overrides of an assignment with a JSON merge patch
+func PatchAssignment(c *gin.Context, q store.Store) {
+	saveOverrides(c, q, false)
+}
+
+func saveOverrides(c *gin.Context, q store.Store, replace bool) {
+	courseID, err := strconv.ParseInt(c.Param("courseID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid course ID",
+		})
+		return
+	}
+	assignmentID, err := strconv.ParseInt(c.Param("assignmentID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid assignment ID",
+		})
+		return
+	}
+
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read body",
+		})
+		return
+	}
+	patch, err := parseFields(body, overrideFields, replace)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	saveAssignment(c,
+		func(ctx context.Context) (sqlite.Assignment, error) {
+			return q.GetAssignment(ctx, sqlite.GetAssignmentParams{ID: assignmentID, CourseID: courseID})
+		},
+		func(ctx context.Context, current sqlite.Assignment) (sqlite.Assignment, error) {
+			// Tasks aren't synced, so there's nothing to override
+			if current.Source != store.SourceCanvas {
+				return writeTask(ctx, q, current, patch)
+			}
+			return writeOverrides(ctx, q, current, patch)
+		})
+}
+
+// Writes an assignment with optimistic concurrency: load reads it as it is now, and
+// write saves the new version or returns sql.ErrNoRows when it changed in the meantime.
+// Responds with the saved assignment and its ETag.
+func saveAssignment(c *gin.Context, load func(ctx context.Context) (sqlite.Assignment, error), write func(ctx context.Context, current sqlite.Assignment) (sqlite.Assignment, error)) {
+	ctx := c.Request.Context()
+	ifMatch := c.GetHeader("If-Match")
+	for attempt := 1; ; attempt++ {
+		current, err := load(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Assignment not found",
+			})
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error fetching assignment: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+			})
+			return
+		}
+		if ifMatch != "" && !etagMatches(ifMatch, assignmentETag(current)) {
+			c.Header("ETag", assignmentETag(current))
+			c.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": "Assignment was changed since it was read",
+			})
+			return
+		}
+
+		updated, err := write(ctx, current)
+		if errors.Is(err, sql.ErrNoRows) && ifMatch == "" && attempt < maxUpdateAttempts {
+			continue
+		}
+		var invalid invalidFieldError
+		if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": invalid.Error(),
+			})
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": "Assignment was changed since it was read",
+			})
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error updating assignment: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+			})
+			return
+		}
+
+		cacheAssignment(ctx, updated, current.CourseID)
+		c.Header("ETag", assignmentETag(updated))
+		c.JSON(http.StatusOK, updated)
+		return
+	}
+}
+
+// Drops the lists and courses a written assignment is in, including the one it was
+// moved out of, then caches the assignment as it is now
+func cacheAssignment(ctx context.Context, a sqlite.Assignment, previousCourseID int64) {
+	r := redis.GetInstance()
+	if err := r.InvalidateContext(ctx, redis.AssignmentInvalidation(a.CourseID, a.ID)); err != nil {
+		fmt.Printf("Error invalidating cached assignment: %v\n", err)
+	}
+	if previousCourseID != a.CourseID {
+		if err := r.InvalidateContext(ctx, redis.AssignmentInvalidation(previousCourseID, a.ID)); err != nil {
+			fmt.Printf("Error invalidating cached assignment: %v\n", err)
+		}
+	}
+	redis.Put(ctx, r, redis.KindAssignment, redis.Key(redis.KindAssignment, a.CourseID, a.ID), readCacheOptions, a)
+}
+
+// Applies the patch to the assignment's overrides and saves both in one transaction, so
+// a failure can't leave the assignment changed without the override that explains it.
+// Returns sql.ErrNoRows when the assignment was changed since current was read.
+func writeOverrides(ctx context.Context, q store.Store, current sqlite.Assignment, patch fieldPatch) (sqlite.Assignment, error) {
+	var updated sqlite.Assignment
+	err := q.WithTx(ctx, func(q store.Store) error {
+		var err error
+		updated, err = applyOverrides(ctx, q, current, patch)
+		return err
+	})
+	if err != nil {
+		return current, err
+	}
+	return updated, nil
+}
+
+func applyOverrides(ctx context.Context, q store.Store, current sqlite.Assignment, patch fieldPatch) (sqlite.Assignment, error) {
+	existing, err := q.GetAssignmentOverride(ctx, current.ID)
+	found := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return current, fmt.Errorf("failed to fetch overrides: %v", err)
+	}
+	if !found {
+		// Nothing was overridden yet, so the stored values are Canvas's
+		existing = sqlite.AssignmentOverride{
+			AssignmentID:  current.ID,
+			CanvasName:    current.Name,
+			CanvasDueDate: current.DueDate,
+		}
+	}
+	override := patch.applyOverride(existing)
+
+	params := sqlite.UpdateAssignmentIfVersionParams{
+		CourseID:   current.CourseID,
+		ID:         current.ID,
+		Name:       override.CanvasName,
+		DueDate:    override.CanvasDueDate,
+		Difficulty: current.Difficulty,
+		Length:     current.Length,
+		Version:    current.Version,
+	}
+	if override.Name.Valid {
+		params.Name = override.Name.String
+	}
+	if override.DueDate.Valid {
+		params.DueDate = override.DueDate
+	}
+	// A cleared estimate is left for the next /prioritize to fill in
+	if override.Difficulty.Valid || existing.Difficulty.Valid {
+		params.Difficulty = override.Difficulty
+	}
+	if override.Length.Valid || existing.Length.Valid {
+		params.Length = override.Length
+	}
+
+	updated, err := q.UpdateAssignmentIfVersion(ctx, params)
+	if err != nil {
+		return current, err
+	}
+
+	if !override.Name.Valid && !override.DueDate.Valid && !override.Difficulty.Valid && !override.Length.Valid {
+		if found {
+			err = q.DeleteAssignmentOverride(ctx, current.ID)
+		}
+	} else {
+		err = q.UpsertAssignmentOverride(ctx, sqlite.UpsertAssignmentOverrideParams{
+			AssignmentID:  override.AssignmentID,
+			Name:          override.Name,
+			DueDate:       override.DueDate,
+			Difficulty:    override.Difficulty,
+			Length:        override.Length,
+			CanvasName:    override.CanvasName,
+			CanvasDueDate: override.CanvasDueDate,
+			UpdatedAt:     time.Now().UTC(),
+		})
+	}
+	if err != nil {
+		return current, fmt.Errorf("failed to save overrides: %v", err)
+	}
+	return updated, nil
+}
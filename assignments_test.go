@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+func TestParseFields(t *testing.T) {
+	due := time.Date(2025, 5, 1, 23, 59, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		body    string
+		replace bool
+		set     []string
+		values  assignmentFields
+		err     string
+	}{
+		{
+			name: "merge patch sets only what's in the body",
+			body: `{"name": "  Lab 1  ", "difficulty": 4}`,
+			set:  []string{"name", "difficulty"},
+			values: assignmentFields{
+				Name:       sql.NullString{String: "Lab 1", Valid: true},
+				Difficulty: sql.NullInt64{Int64: 4, Valid: true},
+			},
+		},
+		{
+			name: "null clears a field",
+			body: `{"due_date": null, "length": 0}`,
+			set:  []string{"due_date", "length"},
+			values: assignmentFields{
+				Length: sql.NullInt64{Int64: 0, Valid: true},
+			},
+		},
+		{
+			name:    "replace clears what's missing",
+			body:    `{"due_date": "2025-05-01T16:59:00-07:00"}`,
+			replace: true,
+			set:     []string{"name", "due_date", "difficulty", "length"},
+			values: assignmentFields{
+				DueDate: sql.NullTime{Time: due, Valid: true},
+			},
+		},
+		{name: "empty object", body: `{}`, set: []string{}},
+		{name: "not an object", body: `[1, 2]`, err: "body must be a JSON object"},
+		{name: "null body", body: `null`, err: "body must be a JSON object"},
+		{name: "unknown field", body: `{"points": 10}`, err: "points can't be changed"},
+		{name: "blank name", body: `{"name": "   "}`, err: "name must be between 1 and 255 characters"},
+		{name: "name isn't a string", body: `{"name": 5}`, err: "name must be a string"},
+		{name: "date without a zone", body: `{"due_date": "2025-05-01"}`, err: "due_date must be an RFC3339 date, e.g. 2025-05-01T23:59:00Z"},
+		{name: "difficulty too high", body: `{"difficulty": 11}`, err: "difficulty must be an integer from 1 to 10"},
+		{name: "difficulty isn't whole", body: `{"difficulty": 2.5}`, err: "difficulty must be an integer from 1 to 10"},
+		{name: "negative length", body: `{"length": -5}`, err: "length must be a non-negative number of minutes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := parseFields([]byte(tt.body), overrideFields, tt.replace)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("parseFields() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(patch.set) != len(tt.set) {
+				t.Errorf("set = %v, want %v", patch.set, tt.set)
+			}
+			for _, name := range tt.set {
+				if !patch.set[name] {
+					t.Errorf("%s isn't set, set = %v", name, patch.set)
+				}
+			}
+			if patch.values != tt.values {
+				t.Errorf("values = %+v, want %+v", patch.values, tt.values)
+			}
+		})
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	tests := []struct {
+		ifMatch string
+		etag    string
+		want    bool
+	}{
+		{`"3"`, `"3"`, true},
+		{`"2"`, `"3"`, false},
+		{`*`, `"3"`, true},
+		{`"1", "3"`, `"3"`, true},
+		{`"1","2"`, `"3"`, false},
+		{`W/"3"`, `"3"`, false},
+		{`3`, `"3"`, false},
+		{``, `"3"`, false},
+	}
+	for _, tt := range tests {
+		if got := etagMatches(tt.ifMatch, tt.etag); got != tt.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", tt.ifMatch, tt.etag, got, tt.want)
+		}
+	}
+}
+
+// Fails to save any override, inside transactions too
+type failingOverrides struct {
+	store.Store
+}
+
+func (s failingOverrides) UpsertAssignmentOverride(ctx context.Context, arg sqlite.UpsertAssignmentOverrideParams) error {
+	return errors.New("disk I/O error")
+}
+
+func (s failingOverrides) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	return s.Store.WithTx(ctx, func(tx store.Store) error {
+		return fn(failingOverrides{tx})
+	})
+}
+
+func TestWriteOverridesRollsBack(t *testing.T) {
+	ctx := context.Background()
+	q := newTestStore(t)
+	if _, err := q.UpsertCourse(ctx, sqlite.UpsertCourseParams{ID: 449, Name: "CPSC 449"}); err != nil {
+		t.Fatal(err)
+	}
+	current, err := q.UpsertAssignment(ctx, sqlite.UpsertAssignmentParams{ID: 1, CourseID: 449, Name: "Lab 1", Published: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := parseFields([]byte(`{"name": "Lab 1 (take home)"}`), overrideFields, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := writeOverrides(ctx, failingOverrides{q}, current, patch); err == nil {
+		t.Fatal("writeOverrides() succeeded without saving the override")
+	}
+	// The rename was rolled back along with the override
+	after, err := q.GetAssignment(ctx, sqlite.GetAssignmentParams{ID: 1, CourseID: 449})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Name != "Lab 1" || after.Version != current.Version {
+		t.Errorf("assignment = %q version %d, want it untouched (%q version %d)", after.Name, after.Version, current.Name, current.Version)
+	}
+
+	// And both are saved when nothing fails
+	updated, err := writeOverrides(ctx, q, current, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	override, err := q.GetAssignmentOverride(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Lab 1 (take home)" || override.Name.String != "Lab 1 (take home)" || override.CanvasName != "Lab 1" {
+		t.Errorf("assignment %q, override %+v", updated.Name, override)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// How far back /changes looks when no ?since= is given
+const defaultChangesWindow = 7 * 24 * time.Hour
+
+// Gets what changed on Canvas (new, renamed, moved or removed assignments) since the
+// given RFC3339 time, oldest first. Not cached, ?since= defaults to a moving window
+// so the same key would hardly ever be asked for twice.
+func GetChanges(c *gin.Context, q store.Store) {
+	since := time.Now().UTC().Add(-defaultChangesWindow)
+	if s := c.Query("since"); s != "" {
+		parsed, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid since, expected an RFC3339 time",
+			})
+			return
+		}
+		since = parsed.UTC()
+	}
+
+	events, err := q.ListAssignmentEventsSince(c.Request.Context(), since)
+	if err != nil {
+		fmt.Printf("Error fetching assignment events from DB: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	if events == nil {
+		events = []sqlite.AssignmentEvent{}
+	}
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// A course along with the number of assignments stored for it
+type CourseWithCount struct {
+	sqlite.Course
+	AssignmentCount int64 `json:"assignment_count"`
+}
+
+// Gets all courses stored in the DB
+func GetAllCourses(c *gin.Context, q store.Store) {
+	courses, err := redis.GetOrLoad(c.Request.Context(), redis.GetInstance(), redis.KindCourseList,
+		redis.Key(redis.KindCourseList), readCacheOptions, q.ListAllCourses)
+	if err != nil {
+		fmt.Printf("Error fetching courses from DB: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	if courses == nil {
+		courses = []sqlite.Course{}
+	}
+	c.JSON(http.StatusOK, courses)
+}
+
+// Gets an individual course along with its assignment count
+func GetCourse(c *gin.Context, q store.Store) {
+	courseID, err := strconv.ParseInt(c.Param("courseID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid course ID",
+		})
+		return
+	}
+
+	course, err := redis.GetOrLoad(c.Request.Context(), redis.GetInstance(), redis.KindCourse,
+		redis.Key(redis.KindCourse, courseID), readCacheOptions,
+		func(ctx context.Context) (CourseWithCount, error) {
+			return loadCourse(ctx, q, courseID)
+		})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Course not found",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error fetching course from DB: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, course)
+}
+
+func loadCourse(ctx context.Context, q store.Store, courseID int64) (CourseWithCount, error) {
+	course, err := q.GetCourse(ctx, courseID)
+	if err != nil {
+		return CourseWithCount{}, err
+	}
+
+	counts, err := q.GetAssignmentCountsByCourse(ctx)
+	if err != nil {
+		return CourseWithCount{}, fmt.Errorf("failed to count assignments: %v", err)
+	}
+
+	resp := CourseWithCount{Course: course}
+	for _, count := range counts {
+		if count.CourseID == courseID {
+			resp.AssignmentCount = count.AssignmentCount
+			break
+		}
+	}
+	return resp, nil
+}
+
+// Gets the assignments of a course, ordered by due date
+func GetCourseAssignments(c *gin.Context, q store.Store) {
+	courseID, err := strconv.ParseInt(c.Param("courseID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid course ID",
+		})
+		return
+	}
+
+	assignments, err := redis.GetOrLoad(c.Request.Context(), redis.GetInstance(), redis.KindCourseAssignments,
+		redis.Key(redis.KindCourseAssignments, courseID), readCacheOptions,
+		func(ctx context.Context) ([]sqlite.ListAssignmentsByCourseRow, error) {
+			// A course without assignments is fine, a course that doesn't exist isn't
+			if _, err := q.GetCourse(ctx, courseID); err != nil {
+				return nil, err
+			}
+			return q.ListAssignmentsByCourse(ctx, courseID)
+		})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Course not found",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error fetching assignments from DB: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, assignments)
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Columns added to the tables of canvas.db before there were migrations. 0001_init
+// creates its tables IF NOT EXISTS, which leaves the tables of an older canvas.db
+// alone, so these have to be added to them first.
+var addedColumns = []struct {
+	table      string
+	column     string
+	definition string
+}{
+	{"courses", "term_id", "INTEGER"},
+	{"courses", "term_name", "TEXT"},
+	{"courses", "start_at", "DATETIME"},
+	{"courses", "end_at", "DATETIME"},
+
+	{"assignments", "points_possible", "REAL"},
+	{"assignments", "submission_types", "TEXT"},
+	{"assignments", "unlock_at", "DATETIME"},
+	{"assignments", "lock_at", "DATETIME"},
+	{"assignments", "html_url", "TEXT"},
+	{"assignments", "published", "BOOLEAN NOT NULL DEFAULT 1"},
+	{"assignments", "assignment_group_id", "INTEGER"},
+	{"assignments", "description", "TEXT"},
+}
+
+// Adds the columns a canvas.db created from an older schema.sql is missing, so it
+// matches 0001_init once the missing tables are created
+func upgradeLegacySQLite(ctx context.Context, db *sql.DB) error {
+	columns := make(map[string]map[string]bool)
+	for _, added := range addedColumns {
+		if columns[added.table] == nil {
+			existing, err := tableColumns(ctx, db, added.table)
+			if err != nil {
+				return fmt.Errorf("failed to read columns of %s: %v", added.table, err)
+			}
+			columns[added.table] = existing
+		}
+		if columns[added.table][added.column] {
+			continue
+		}
+
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition)
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to add %s.%s: %v", added.table, added.column, err)
+		}
+		columns[added.table][added.column] = true
+	}
+	return nil
+}
+
+func tableColumns(ctx context.Context, db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			typ       string
+			notNull   bool
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
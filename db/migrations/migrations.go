@@ -0,0 +1,361 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Each dialect has its own directory of numbered migrations, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. A SQLite migration that needs
+// FTS5 also has a <version>_<name>.nofts5.up.sql and .nofts5.down.sql, run instead when
+// SQLite was built without it (no sqlite_fts5 build tag).
+//
+//go:embed sqlite/*.sql postgres/*.sql
+var files embed.FS
+
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)(\.nofts5)?\.(up|down)\.sql$`)
+
+// Keeps track of which migrations were applied, works the same in both dialects
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)`
+
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+
+	// The scripts run without FTS5, empty when the migration doesn't need it
+	upNoFTS5   string
+	downNoFTS5 string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// A migration and when it was applied, AppliedAt is nil while it's pending
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Applies and rolls back the embedded migrations of a dialect
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+func New(db *sql.DB, dialect string) (*Migrator, error) {
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// Reads the dialect's migrations sorted by version, each one needs both an up and a
+// down file
+func load(dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("unknown dialect %q", dialect)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %v", entry.Name(), err)
+		}
+		script, err := fs.ReadFile(files, path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, found := byVersion[version]
+		if !found {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", m.Name, match[2], version)
+		}
+		switch noFTS5 := match[3] != ""; {
+		case match[4] == "up" && noFTS5:
+			m.upNoFTS5 = string(script)
+		case match[4] == "up":
+			m.up = string(script)
+		case noFTS5:
+			m.downNoFTS5 = string(script)
+		default:
+			m.down = string(script)
+		}
+	}
+
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down file", m)
+		}
+		if (m.upNoFTS5 == "") != (m.downNoFTS5 == "") {
+			return nil, fmt.Errorf("migration %s needs both a nofts5 up and a nofts5 down file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return int(a.Version - b.Version)
+	})
+	return migrations, nil
+}
+
+// Applies every pending migration in order and returns the ones that were applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := m.prepare(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.run(ctx, migration, true); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Rolls back the last n applied migrations, newest first, and returns the ones that
+// were rolled back
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	if err := m.prepare(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if err := m.run(ctx, migration, false); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Lists every migration along with when it was applied, without changing the
+// database. Every migration of a canvas.db from before migrations is pending.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Creates schema_migrations if needed. A canvas.db from before migrations is brought
+// up to date first.
+func (m *Migrator) prepare(ctx context.Context) error {
+	legacy, err := m.Legacy(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to inspect database: %v", err)
+	}
+	if legacy {
+		if err := upgradeLegacySQLite(ctx, m.db); err != nil {
+			return err
+		}
+	}
+
+	if _, err := m.db.ExecContext(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+	return nil
+}
+
+// Returns when each applied version was applied, none before schema_migrations exists
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	applied := make(map[int64]time.Time)
+	exists, err := m.hasMigrationsTable(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect database: %v", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := m.db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list applied migrations: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) hasMigrationsTable(ctx context.Context) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM sqlite_master
+WHERE type = 'table' AND name = 'schema_migrations')`
+	if m.dialect == DialectPostgres {
+		query = `SELECT EXISTS (SELECT 1 FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_name = 'schema_migrations')`
+	}
+	var exists bool
+	err := m.db.QueryRowContext(ctx, query).Scan(&exists)
+	return exists, err
+}
+
+// Whether the database is a canvas.db from before migrations, which Up upgrades
+func (m *Migrator) Legacy(ctx context.Context) (bool, error) {
+	if m.dialect != DialectSQLite {
+		return false, nil
+	}
+	return m.isLegacySQLite(ctx)
+}
+
+// A canvas.db created before migrations has the courses table but no schema_migrations
+func (m *Migrator) isLegacySQLite(ctx context.Context) (bool, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT name FROM sqlite_master
+WHERE type = 'table' AND name IN ('courses', 'schema_migrations')`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	tables := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, err
+		}
+		tables[name] = true
+	}
+	return tables["courses"] && !tables["schema_migrations"], rows.Err()
+}
+
+// Runs one direction of a migration and records it in the same transaction, so a
+// failed migration leaves nothing behind
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	script, direction := migration.up, "up"
+	if !up {
+		script, direction = migration.down, "down"
+	}
+	if migration.upNoFTS5 != "" {
+		fts5, err := m.hasFTS5(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to check for FTS5: %v", err)
+		}
+		if !fts5 && up {
+			script, direction = migration.upNoFTS5, "up (without FTS5)"
+		} else if !fts5 {
+			script, direction = migration.downNoFTS5, "down (without FTS5)"
+		}
+	}
+
+	// Only begun once FTS5 was checked for, a database limited to one connection would
+	// wait on the check forever otherwise
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("failed to migrate %s %s: %v", migration, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, m.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, m.rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %s: %v", migration, err)
+	}
+
+	return tx.Commit()
+}
+
+// Whether SQLite was built with FTS5, which only the sqlite_fts5 build tag turns on
+func (m *Migrator) hasFTS5(ctx context.Context) (bool, error) {
+	if m.dialect != DialectSQLite {
+		return true, nil
+	}
+	var used bool
+	err := m.db.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&used)
+	return used, err
+}
+
+// Postgres wants numbered placeholders ($1, $2, ...) instead of ?
+func (m *Migrator) rebind(query string) string {
+	if m.dialect != DialectPostgres {
+		return query
+	}
+	var rebound []byte
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if query[i] != '?' {
+			rebound = append(rebound, query[i])
+			continue
+		}
+		n++
+		rebound = append(rebound, '$')
+		rebound = strconv.AppendInt(rebound, int64(n), 10)
+	}
+	return string(rebound)
+}
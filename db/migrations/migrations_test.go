@@ -0,0 +1,82 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestStatusLeavesLegacyDatabaseAlone(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: gets its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	// The tables of the schema.sql from before migrations
+	for _, stmt := range []string{
+		`CREATE TABLE courses (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+)`,
+		`CREATE TABLE assignments (
+    id INTEGER PRIMARY KEY,
+    course_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    due_date DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    difficulty INTEGER CHECK(difficulty BETWEEN 1 AND 10),
+    length INTEGER,
+    FOREIGN KEY(course_id) REFERENCES courses(id) ON DELETE CASCADE
+)`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := New(db, DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("%s is applied, want pending", status.Migration)
+		}
+	}
+	if legacy, err := m.Legacy(ctx); err != nil || !legacy {
+		t.Errorf("Legacy() = %v, %v, want true", legacy, err)
+	}
+
+	columns, err := tableColumns(ctx, db, "courses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if columns["term_id"] {
+		t.Error("Status() added columns to courses")
+	}
+	if exists, err := m.hasMigrationsTable(ctx); err != nil || exists {
+		t.Errorf("schema_migrations exists after Status() = %v, %v, want false", exists, err)
+	}
+
+	// Up is what upgrades it
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if columns, err := tableColumns(ctx, db, "courses"); err != nil || !columns["term_id"] {
+		t.Errorf("courses.term_id after Up() = %v, %v, want added", columns["term_id"], err)
+	}
+	if legacy, err := m.Legacy(ctx); err != nil || legacy {
+		t.Errorf("Legacy() after Up() = %v, %v, want false", legacy, err)
+	}
+}
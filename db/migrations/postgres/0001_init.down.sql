@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_assignment_events_created_at;
+DROP INDEX IF EXISTS idx_sync_runs_started_at;
+DROP INDEX IF EXISTS idx_assignments_due_date;
+DROP INDEX IF EXISTS idx_assignments_course_id;
+
+DROP TABLE IF EXISTS course_sync_state;
+DROP TABLE IF EXISTS assignment_events;
+DROP TABLE IF EXISTS sync_runs;
+DROP TABLE IF EXISTS submissions;
+DROP TABLE IF EXISTS assignments;
+DROP TABLE IF EXISTS courses;
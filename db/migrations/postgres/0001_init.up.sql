@@ -0,0 +1,91 @@
+-- Same schema as sqlite/0001_init.up.sql. Canvas IDs can get past 32 bits, so they're
+-- all BIGINT.
+CREATE TABLE IF NOT EXISTS courses (
+    id BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    term_id BIGINT,    -- Canvas enrollment term the course belongs to
+    term_name TEXT,
+    start_at TIMESTAMP WITH TIME ZONE, -- Falls back to the term dates when the course has none
+    end_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS assignments (
+    id BIGINT PRIMARY KEY,
+    course_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    due_date TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    difficulty BIGINT CHECK(difficulty BETWEEN 1 AND 10),
+    length BIGINT,     -- Estimated minutes of work
+    points_possible DOUBLE PRECISION,
+    submission_types TEXT,  -- Comma separated, e.g. online_upload,online_text_entry
+    unlock_at TIMESTAMP WITH TIME ZONE,
+    lock_at TIMESTAMP WITH TIME ZONE,
+    html_url TEXT,
+    published BOOLEAN NOT NULL DEFAULT TRUE,
+    assignment_group_id BIGINT,
+    description TEXT,
+
+    CONSTRAINT fk_course
+        FOREIGN KEY(course_id)
+        REFERENCES courses(id)
+        ON DELETE CASCADE
+);
+
+-- The user's own submission for each assignment, as of the last sync
+CREATE TABLE IF NOT EXISTS submissions (
+    assignment_id BIGINT PRIMARY KEY,
+    workflow_state TEXT NOT NULL,  -- unsubmitted | submitted | graded | pending_review
+    submitted_at TIMESTAMP WITH TIME ZONE,
+    score DOUBLE PRECISION,
+    late BOOLEAN NOT NULL DEFAULT FALSE,
+    missing BOOLEAN NOT NULL DEFAULT FALSE,
+    excused BOOLEAN NOT NULL DEFAULT FALSE,
+
+    CONSTRAINT fk_assignment
+        FOREIGN KEY(assignment_id)
+        REFERENCES assignments(id)
+        ON DELETE CASCADE
+);
+
+-- One row per Canvas sync, whether it was scheduled or triggered by a request
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id BIGSERIAL PRIMARY KEY,
+    source TEXT NOT NULL,  -- scheduled | manual
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    courses_synced BIGINT NOT NULL DEFAULT 0,
+    assignments_synced BIGINT NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+-- What changed on Canvas between syncs, e.g. a moved due date. There's no foreign key
+-- since events outlive the assignments removed from Canvas.
+CREATE TABLE IF NOT EXISTS assignment_events (
+    id BIGSERIAL PRIMARY KEY,
+    assignment_id BIGINT NOT NULL,
+    course_id BIGINT NOT NULL,
+    event_type TEXT NOT NULL,  -- created | due_date_changed | renamed | removed_from_canvas
+    old_value TEXT,
+    new_value TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- How each course was last synced. The ETag of its assignment list lets the next sync
+-- skip the course when nothing changed on Canvas.
+CREATE TABLE IF NOT EXISTS course_sync_state (
+    course_id BIGINT PRIMARY KEY,
+    etag TEXT,  -- NULL when the list spans multiple pages
+    last_synced_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    CONSTRAINT fk_course
+        FOREIGN KEY(course_id)
+        REFERENCES courses(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignments_course_id ON assignments(course_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_due_date ON assignments(due_date);
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started_at ON sync_runs(started_at);
+CREATE INDEX IF NOT EXISTS idx_assignment_events_created_at ON assignment_events(created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS assignment_overrides;
+
+ALTER TABLE assignments DROP COLUMN IF EXISTS version;
@@ -0,0 +1,18 @@
+-- Same as sqlite/0002_assignment_overrides.up.sql
+ALTER TABLE assignments ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS assignment_overrides (
+    assignment_id BIGINT PRIMARY KEY,
+    name TEXT,
+    due_date TIMESTAMP WITH TIME ZONE,
+    difficulty BIGINT CHECK(difficulty BETWEEN 1 AND 10),
+    length BIGINT CHECK(length >= 0),
+    canvas_name TEXT NOT NULL,
+    canvas_due_date TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    CONSTRAINT fk_assignment
+        FOREIGN KEY(assignment_id)
+        REFERENCES assignments(id)
+        ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+-- Same as sqlite/0003_assignment_source.up.sql
+ALTER TABLE assignments ADD COLUMN source TEXT NOT NULL DEFAULT 'canvas'
+    CHECK(source IN ('canvas', 'manual', 'syllabus'));
+
+INSERT INTO courses (id, name) VALUES (0, 'Personal')
+ON CONFLICT(id) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_source ON assignments(source);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_syllabi_search;
+DROP INDEX IF EXISTS idx_assignments_search;
+
+DROP TABLE IF EXISTS syllabi;
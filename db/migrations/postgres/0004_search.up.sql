@@ -0,0 +1,22 @@
+-- Same as sqlite/0004_search.up.sql, except Postgres searches with GIN indexes on the
+-- text's tsvector rather than FTS5 tables. Indexes are always up to date, so there are
+-- no triggers; the search queries have to use the exact same expressions.
+CREATE TABLE IF NOT EXISTS syllabi (
+    id BIGSERIAL PRIMARY KEY,
+    course_id BIGINT NOT NULL,
+    filename TEXT NOT NULL,
+    content TEXT NOT NULL,
+    uploaded_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE(course_id, filename),
+
+    CONSTRAINT fk_course
+        FOREIGN KEY(course_id)
+        REFERENCES courses(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_assignments_search ON assignments
+    USING GIN (to_tsvector('english', name || ' ' || COALESCE(description, '')));
+
+CREATE INDEX IF NOT EXISTS idx_syllabi_search ON syllabi
+    USING GIN (to_tsvector('english', filename || ' ' || content));
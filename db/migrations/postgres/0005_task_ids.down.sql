@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS task_id_seq;
@@ -0,0 +1,6 @@
+-- Same as sqlite/0005_task_ids.up.sql, except a sequence hands out the IDs. It counts
+-- down from below the lowest task there already is.
+CREATE SEQUENCE IF NOT EXISTS task_id_seq INCREMENT BY -1 MAXVALUE -1 START WITH -1;
+
+SELECT setval('task_id_seq', COALESCE(MIN(id) - 1, -1), false)
+FROM assignments WHERE id < 0;
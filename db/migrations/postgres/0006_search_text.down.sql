@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_assignments_search;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_search ON assignments
+    USING GIN (to_tsvector('english', name || ' ' || COALESCE(description, '')));
+
+ALTER TABLE assignments DROP COLUMN IF EXISTS description_text;
@@ -0,0 +1,8 @@
+-- Same as sqlite/0006_search_text.up.sql: the search index reads the description's
+-- text rather than its HTML
+ALTER TABLE assignments ADD COLUMN IF NOT EXISTS description_text TEXT;
+
+DROP INDEX IF EXISTS idx_assignments_search;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_search ON assignments
+    USING GIN (to_tsvector('english', name || ' ' || COALESCE(description_text, '')));
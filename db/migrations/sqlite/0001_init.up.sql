@@ -0,0 +1,83 @@
+-- Full schema as of the switch to versioned migrations. Everything is IF NOT EXISTS
+-- so databases created before then can be adopted, later changes go in new files.
+CREATE TABLE IF NOT EXISTS courses (
+    id INTEGER PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    term_id INTEGER,   -- Canvas enrollment term the course belongs to
+    term_name TEXT,
+    start_at DATETIME, -- Falls back to the term dates when the course has none
+    end_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS assignments (
+    id INTEGER PRIMARY KEY,
+    course_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    due_date DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    difficulty INTEGER CHECK(difficulty BETWEEN 1 AND 10),
+    length INTEGER,    -- Estimated minutes of work
+    points_possible REAL,
+    submission_types TEXT,  -- Comma separated, e.g. online_upload,online_text_entry
+    unlock_at DATETIME,
+    lock_at DATETIME,
+    html_url TEXT,
+    published BOOLEAN NOT NULL DEFAULT 1,
+    assignment_group_id INTEGER,
+    description TEXT,
+    FOREIGN KEY(course_id) REFERENCES courses(id)
+    ON DELETE CASCADE
+);
+
+-- The user's own submission for each assignment, as of the last sync
+CREATE TABLE IF NOT EXISTS submissions (
+    assignment_id INTEGER PRIMARY KEY,
+    workflow_state TEXT NOT NULL,  -- unsubmitted | submitted | graded | pending_review
+    submitted_at DATETIME,
+    score REAL,
+    late BOOLEAN NOT NULL DEFAULT 0,
+    missing BOOLEAN NOT NULL DEFAULT 0,
+    excused BOOLEAN NOT NULL DEFAULT 0,
+    FOREIGN KEY(assignment_id) REFERENCES assignments(id)
+    ON DELETE CASCADE
+);
+
+-- One row per Canvas sync, whether it was scheduled or triggered by a request
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL,  -- scheduled | manual
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    courses_synced INTEGER NOT NULL DEFAULT 0,
+    assignments_synced INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+-- What changed on Canvas between syncs, e.g. a moved due date. There's no foreign key
+-- since events outlive the assignments removed from Canvas.
+CREATE TABLE IF NOT EXISTS assignment_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    assignment_id INTEGER NOT NULL,
+    course_id INTEGER NOT NULL,
+    event_type TEXT NOT NULL,  -- created | due_date_changed | renamed | removed_from_canvas
+    old_value TEXT,
+    new_value TEXT,
+    created_at DATETIME NOT NULL
+);
+
+-- How each course was last synced. The ETag of its assignment list lets the next sync
+-- skip the course when nothing changed on Canvas.
+CREATE TABLE IF NOT EXISTS course_sync_state (
+    course_id INTEGER PRIMARY KEY,
+    etag TEXT,  -- NULL when the list spans multiple pages
+    last_synced_at DATETIME NOT NULL,
+    FOREIGN KEY(course_id) REFERENCES courses(id)
+    ON DELETE CASCADE
+);
+
+-- Index for faster lookups (optional in SQLite, but can improve performance)
+CREATE INDEX IF NOT EXISTS idx_assignments_course_id ON assignments(course_id);
+CREATE INDEX IF NOT EXISTS idx_assignments_due_date ON assignments(due_date);
+CREATE INDEX IF NOT EXISTS idx_sync_runs_started_at ON sync_runs(started_at);
+CREATE INDEX IF NOT EXISTS idx_assignment_events_created_at ON assignment_events(created_at);
@@ -0,0 +1,19 @@
+-- Bumped on every write to an assignment, it's what the ETag of
+-- /:courseID/assignments/:assignmentID is made from
+ALTER TABLE assignments ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+-- Fields the user set on an assignment themselves, which win over Canvas and the AI
+-- estimates until they're cleared. NULL means the field isn't overridden. Canvas's own
+-- name and due date are kept so clearing an override can put them back right away.
+CREATE TABLE IF NOT EXISTS assignment_overrides (
+    assignment_id INTEGER PRIMARY KEY,
+    name TEXT,
+    due_date DATETIME,
+    difficulty INTEGER CHECK(difficulty BETWEEN 1 AND 10),
+    length INTEGER CHECK(length >= 0),
+    canvas_name TEXT NOT NULL,
+    canvas_due_date DATETIME,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY(assignment_id) REFERENCES assignments(id)
+    ON DELETE CASCADE
+);
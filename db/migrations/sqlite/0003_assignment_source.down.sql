@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_assignments_source;
+
+DELETE FROM assignments WHERE source <> 'canvas';
+DELETE FROM courses WHERE id = 0;
+
+ALTER TABLE assignments DROP COLUMN source;
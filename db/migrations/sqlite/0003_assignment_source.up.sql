@@ -0,0 +1,10 @@
+-- Where each assignment came from. Only canvas ones are touched by the sync, the others
+-- were created through /tasks and get negative IDs so they never collide with Canvas's.
+ALTER TABLE assignments ADD COLUMN source TEXT NOT NULL DEFAULT 'canvas'
+    CHECK(source IN ('canvas', 'manual', 'syllabus'));
+
+-- Home of the tasks that don't belong to any Canvas course
+INSERT INTO courses (id, name) VALUES (0, 'Personal')
+ON CONFLICT(id) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_assignments_source ON assignments(source);
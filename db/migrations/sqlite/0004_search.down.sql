@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS syllabi_fts_update;
+DROP TRIGGER IF EXISTS syllabi_fts_delete;
+DROP TRIGGER IF EXISTS syllabi_fts_insert;
+DROP TRIGGER IF EXISTS assignments_fts_update;
+DROP TRIGGER IF EXISTS assignments_fts_delete;
+DROP TRIGGER IF EXISTS assignments_fts_insert;
+
+DROP TABLE IF EXISTS syllabi_fts;
+DROP TABLE IF EXISTS assignments_fts;
+DROP TABLE IF EXISTS syllabi;
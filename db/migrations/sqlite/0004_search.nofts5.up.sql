@@ -0,0 +1,13 @@
+-- 0004_search for SQLite built without FTS5 (no sqlite_fts5 build tag). There are no
+-- full-text indexes, /search falls back to LIKE over the same columns, see
+-- store.sqliteStore.Search.
+CREATE TABLE IF NOT EXISTS syllabi (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    content TEXT NOT NULL,
+    uploaded_at DATETIME NOT NULL,
+    UNIQUE(course_id, filename),
+    FOREIGN KEY(course_id) REFERENCES courses(id)
+    ON DELETE CASCADE
+);
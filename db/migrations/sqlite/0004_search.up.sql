@@ -0,0 +1,70 @@
+-- Syllabi uploaded through /syllabus, along with the text extracted from them
+CREATE TABLE IF NOT EXISTS syllabi (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    course_id INTEGER NOT NULL,
+    filename TEXT NOT NULL,
+    content TEXT NOT NULL,
+    uploaded_at DATETIME NOT NULL,
+    UNIQUE(course_id, filename),
+    FOREIGN KEY(course_id) REFERENCES courses(id)
+    ON DELETE CASCADE
+);
+
+-- Full-text indexes behind /search. They're external content tables: the text stays in
+-- assignments and syllabi, and the triggers below keep the indexes in step with it.
+-- Needs SQLite built with FTS5, i.e. the sqlite_fts5 build tag, see 0004_search.nofts5
+-- for the migration run without it.
+CREATE VIRTUAL TABLE IF NOT EXISTS assignments_fts USING fts5(
+    name,
+    description,
+    content = 'assignments',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS syllabi_fts USING fts5(
+    filename,
+    content,
+    content = 'syllabi',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+-- An external content index can only drop a row given the values it was indexed with,
+-- so updates remove the old values before adding the new ones
+CREATE TRIGGER IF NOT EXISTS assignments_fts_insert AFTER INSERT ON assignments BEGIN
+    INSERT INTO assignments_fts (rowid, name, description)
+    VALUES (new.id, new.name, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_delete AFTER DELETE ON assignments BEGIN
+    INSERT INTO assignments_fts (assignments_fts, rowid, name, description)
+    VALUES ('delete', old.id, old.name, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_update AFTER UPDATE OF name, description ON assignments BEGIN
+    INSERT INTO assignments_fts (assignments_fts, rowid, name, description)
+    VALUES ('delete', old.id, old.name, old.description);
+    INSERT INTO assignments_fts (rowid, name, description)
+    VALUES (new.id, new.name, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS syllabi_fts_insert AFTER INSERT ON syllabi BEGIN
+    INSERT INTO syllabi_fts (rowid, filename, content)
+    VALUES (new.id, new.filename, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS syllabi_fts_delete AFTER DELETE ON syllabi BEGIN
+    INSERT INTO syllabi_fts (syllabi_fts, rowid, filename, content)
+    VALUES ('delete', old.id, old.filename, old.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS syllabi_fts_update AFTER UPDATE OF filename, content ON syllabi BEGIN
+    INSERT INTO syllabi_fts (syllabi_fts, rowid, filename, content)
+    VALUES ('delete', old.id, old.filename, old.content);
+    INSERT INTO syllabi_fts (rowid, filename, content)
+    VALUES (new.id, new.filename, new.content);
+END;
+
+-- Index the assignments that are already there
+INSERT INTO assignments_fts (assignments_fts) VALUES ('rebuild');
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS task_sequence_insert;
+
+DROP TABLE IF EXISTS task_sequence;
@@ -0,0 +1,16 @@
+-- The last ID handed out to a task. Tasks count down from -1 (see 0003) and an ID is
+-- never handed out twice, even after its task is deleted.
+CREATE TABLE IF NOT EXISTS task_sequence (
+    id INTEGER PRIMARY KEY CHECK(id = 1),
+    last_id INTEGER NOT NULL
+);
+
+INSERT INTO task_sequence (id, last_id)
+SELECT 1, COALESCE(MIN(id), 0) FROM assignments WHERE id < 0;
+
+-- CreateTask takes the ID after last_id in the same statement, so SQLite's single
+-- writer keeps two tasks from getting the same one
+CREATE TRIGGER IF NOT EXISTS task_sequence_insert AFTER INSERT ON assignments
+WHEN new.id < 0 BEGIN
+    UPDATE task_sequence SET last_id = new.id WHERE id = 1 AND last_id > new.id;
+END;
@@ -0,0 +1,35 @@
+DROP TRIGGER IF EXISTS assignments_fts_update;
+DROP TRIGGER IF EXISTS assignments_fts_delete;
+DROP TRIGGER IF EXISTS assignments_fts_insert;
+
+DROP TABLE IF EXISTS assignments_fts;
+
+-- Back to indexing the descriptions as 0004 did
+CREATE VIRTUAL TABLE IF NOT EXISTS assignments_fts USING fts5(
+    name,
+    description,
+    content = 'assignments',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_insert AFTER INSERT ON assignments BEGIN
+    INSERT INTO assignments_fts (rowid, name, description)
+    VALUES (new.id, new.name, new.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_delete AFTER DELETE ON assignments BEGIN
+    INSERT INTO assignments_fts (assignments_fts, rowid, name, description)
+    VALUES ('delete', old.id, old.name, old.description);
+END;
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_update AFTER UPDATE OF name, description ON assignments BEGIN
+    INSERT INTO assignments_fts (assignments_fts, rowid, name, description)
+    VALUES ('delete', old.id, old.name, old.description);
+    INSERT INTO assignments_fts (rowid, name, description)
+    VALUES (new.id, new.name, new.description);
+END;
+
+INSERT INTO assignments_fts (assignments_fts) VALUES ('rebuild');
+
+ALTER TABLE assignments DROP COLUMN description_text;
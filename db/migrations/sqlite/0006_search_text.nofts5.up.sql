@@ -0,0 +1,2 @@
+-- 0006_search_text for SQLite built without FTS5, there's no index to rebuild
+ALTER TABLE assignments ADD COLUMN description_text TEXT;
@@ -0,0 +1,39 @@
+-- Descriptions from Canvas are HTML, so indexing them as is made /search match the
+-- markup (tags, attributes, styles) and return it in the snippets. description_text
+-- holds the description's text without its markup (see syllabus.HTMLText), written
+-- along with the description; the service fills it in for the assignments already
+-- there when it starts.
+ALTER TABLE assignments ADD COLUMN description_text TEXT;
+
+DROP TRIGGER IF EXISTS assignments_fts_update;
+DROP TRIGGER IF EXISTS assignments_fts_delete;
+DROP TRIGGER IF EXISTS assignments_fts_insert;
+
+DROP TABLE IF EXISTS assignments_fts;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS assignments_fts USING fts5(
+    name,
+    description_text,
+    content = 'assignments',
+    content_rowid = 'id',
+    tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_insert AFTER INSERT ON assignments BEGIN
+    INSERT INTO assignments_fts (rowid, name, description_text)
+    VALUES (new.id, new.name, new.description_text);
+END;
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_delete AFTER DELETE ON assignments BEGIN
+    INSERT INTO assignments_fts (assignments_fts, rowid, name, description_text)
+    VALUES ('delete', old.id, old.name, old.description_text);
+END;
+
+CREATE TRIGGER IF NOT EXISTS assignments_fts_update AFTER UPDATE OF name, description_text ON assignments BEGIN
+    INSERT INTO assignments_fts (assignments_fts, rowid, name, description_text)
+    VALUES ('delete', old.id, old.name, old.description_text);
+    INSERT INTO assignments_fts (rowid, name, description_text)
+    VALUES (new.id, new.name, new.description_text);
+END;
+
+INSERT INTO assignments_fts (assignments_fts) VALUES ('rebuild');
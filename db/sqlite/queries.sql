@@ -1,20 +1,37 @@
 -- queries.sql
 -- name: UpsertCourse :one
-INSERT INTO courses (id, name)
-VALUES (?1, ?2)
+INSERT INTO courses (id, name, term_id, term_name, start_at, end_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6)
 ON CONFLICT(id) DO UPDATE SET 
-    name = excluded.name
+    name = excluded.name,
+    term_id = excluded.term_id,
+    term_name = excluded.term_name,
+    start_at = excluded.start_at,
+    end_at = excluded.end_at
 RETURNING *;
 
 -- name: UpsertAssignment :one
-INSERT INTO assignments (id, course_id, name, due_date, difficulty, length)
-VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+INSERT INTO assignments (
+    id, course_id, name, due_date, difficulty, length, points_possible, submission_types,
+    unlock_at, lock_at, html_url, published, assignment_group_id, description, description_text
+)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15)
 ON CONFLICT(id) DO UPDATE SET 
     course_id = excluded.course_id,
     name = excluded.name,
     due_date = excluded.due_date,
     difficulty = excluded.difficulty,
-    length = excluded.length
+    length = excluded.length,
+    points_possible = excluded.points_possible,
+    submission_types = excluded.submission_types,
+    unlock_at = excluded.unlock_at,
+    lock_at = excluded.lock_at,
+    html_url = excluded.html_url,
+    published = excluded.published,
+    assignment_group_id = excluded.assignment_group_id,
+    description = excluded.description,
+    description_text = excluded.description_text,
+    version = assignments.version + 1
 RETURNING *;
 
 -- name: ListAssignmentsByCourse :many
@@ -23,6 +40,10 @@ FROM assignments
 WHERE course_id = ?1
 ORDER BY due_date;
 
+-- name: ListCourseAssignments :many
+SELECT * FROM assignments
+WHERE course_id = ?1;
+
 -- name: DeleteAssignmentsByCourse :exec
 DELETE FROM assignments 
 WHERE course_id = ?1;
@@ -41,7 +62,8 @@ SET
     name = ?2,
     due_date = ?3,
     difficulty = ?4,
-    length = ?5
+    length = ?5,
+    version = version + 1
 WHERE id = ?1;
 
 -- name: GetAssignmentCountsByCourse :many
@@ -65,53 +87,362 @@ WHERE id = ?1 and course_id = ?2;
 -- name: ListAllAssignments :many
 SELECT * FROM assignments;
 
+-- name: ListUpcomingAssignments :many
+SELECT * FROM assignments
+WHERE due_date >= ?1
+ORDER BY due_date
+LIMIT ?2;
+
+-- name: ListPendingAssignments :many
+SELECT * FROM assignments
+WHERE id NOT IN (
+    SELECT assignment_id FROM submissions
+    WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+);
+
+-- name: ListUpcomingPendingAssignments :many
+SELECT * FROM assignments
+WHERE due_date >= ?1
+    AND id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    )
+ORDER BY due_date
+LIMIT ?2;
+
 -- name: ListAllCourses :many
 SELECT * FROM courses;
 
--- -- name: UpsertCourse :one
--- INSERT INTO courses (id, name)
--- VALUES ($1, $2)
--- ON CONFLICT (id) DO UPDATE 
--- SET name = EXCLUDED.name
--- RETURNING *;
---
--- -- name: UpsertAssignment :one
--- INSERT INTO assignments (id, course_id, name, due_date)
--- VALUES ($1, $2, $3, $4)
--- ON CONFLICT (id) DO UPDATE
--- SET 
---     course_id = EXCLUDED.course_id,
---     name = EXCLUDED.name,
---     due_date = EXCLUDED.due_date
--- RETURNING *;
---
--- -- name: ListAssignmentsByCourse :many
--- SELECT id, name, due_date
--- FROM assignments
--- WHERE course_id = $1
--- ORDER BY due_date;
---
--- -- name: DeleteAssignmentsByCourse :exec
--- DELETE FROM assignments 
--- WHERE course_id = $1;
---
--- -- name: UpdateAssignment :exec
--- UPDATE assignments
--- SET 
---     name = $2,
---     due_date = $3
--- WHERE id = $1;
---
--- -- name: GetAssignmentCountsByCourse :many
--- WITH assignment_counts AS (
---     SELECT course_id, COUNT(*) as total_assignments
---     FROM assignments
---     GROUP BY course_id
--- )
--- SELECT 
---     c.id AS course_id,
---     c.name AS course_name,
---     COALESCE(ac.total_assignments, 0) AS assignment_count
--- FROM courses c
--- LEFT JOIN assignment_counts ac ON c.id = ac.course_id
--- ORDER BY assignment_count DESC;
+-- name: GetCourse :one
+SELECT * FROM courses
+WHERE id = ?1;
+
+-- name: CreateSyncRun :one
+INSERT INTO sync_runs (source, started_at)
+VALUES (?1, ?2)
+RETURNING *;
+
+-- name: FinishSyncRun :exec
+UPDATE sync_runs
+SET
+    finished_at = ?2,
+    courses_synced = ?3,
+    assignments_synced = ?4,
+    error = ?5
+WHERE id = ?1;
+
+-- name: ListSyncRuns :many
+SELECT * FROM sync_runs
+ORDER BY started_at DESC
+LIMIT ?1;
+
+-- name: CreateAssignmentEvent :exec
+INSERT INTO assignment_events (assignment_id, course_id, event_type, old_value, new_value, created_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6);
+
+-- name: ListAssignmentEventsSince :many
+SELECT * FROM assignment_events
+WHERE created_at >= ?1
+ORDER BY created_at, id;
+
+-- name: GetCourseSyncState :one
+SELECT * FROM course_sync_state
+WHERE course_id = ?1;
+
+-- name: UpsertCourseSyncState :exec
+INSERT INTO course_sync_state (course_id, etag, last_synced_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT(course_id) DO UPDATE SET
+    etag = excluded.etag,
+    last_synced_at = excluded.last_synced_at;
+
+-- name: UpsertSubmission :exec
+INSERT INTO submissions (assignment_id, workflow_state, submitted_at, score, late, missing, excused)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+ON CONFLICT(assignment_id) DO UPDATE SET
+    workflow_state = excluded.workflow_state,
+    submitted_at = excluded.submitted_at,
+    score = excluded.score,
+    late = excluded.late,
+    missing = excluded.missing,
+    excused = excluded.excused;
+
+-- name: UpdateAssignmentIfVersion :one
+UPDATE assignments
+SET
+    name = ?3,
+    due_date = ?4,
+    difficulty = ?5,
+    length = ?6,
+    version = version + 1
+WHERE course_id = ?1 AND id = ?2 AND version = ?7
+RETURNING *;
+
+-- name: GetAssignmentOverride :one
+SELECT * FROM assignment_overrides
+WHERE assignment_id = ?1;
+
+-- name: ListAssignmentOverridesByCourse :many
+SELECT * FROM assignment_overrides
+WHERE assignment_id IN (
+    SELECT id FROM assignments
+    WHERE course_id = ?1
+);
+
+-- name: UpsertAssignmentOverride :exec
+INSERT INTO assignment_overrides (assignment_id, name, due_date, difficulty, length, canvas_name, canvas_due_date, updated_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+ON CONFLICT(assignment_id) DO UPDATE SET
+    name = excluded.name,
+    due_date = excluded.due_date,
+    difficulty = excluded.difficulty,
+    length = excluded.length,
+    canvas_name = excluded.canvas_name,
+    canvas_due_date = excluded.canvas_due_date,
+    updated_at = excluded.updated_at;
+
+-- name: UpdateAssignmentOverrideCanvas :exec
+UPDATE assignment_overrides
+SET
+    canvas_name = ?2,
+    canvas_due_date = ?3
+WHERE assignment_id = ?1;
+
+-- name: DeleteAssignmentOverride :exec
+DELETE FROM assignment_overrides
+WHERE assignment_id = ?1;
+
+-- name: CreateTask :one
+INSERT INTO assignments (id, course_id, name, due_date, difficulty, length, description, description_text, source)
+VALUES (
+    (SELECT last_id - 1 FROM task_sequence WHERE id = 1),
+    ?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7
+)
+RETURNING *;
+
+-- name: GetTask :one
+SELECT * FROM assignments
+WHERE id = ?1 AND source <> 'canvas';
+
+-- name: ListTasks :many
+SELECT * FROM assignments
+WHERE source <> 'canvas'
+ORDER BY due_date, id;
+
+-- name: UpdateTaskIfVersion :one
+UPDATE assignments
+SET
+    course_id = ?2,
+    name = ?3,
+    due_date = ?4,
+    difficulty = ?5,
+    length = ?6,
+    description = ?7,
+    description_text = ?7,
+    version = version + 1
+WHERE id = ?1 AND version = ?8 AND source <> 'canvas'
+RETURNING *;
+
+-- name: DeleteTask :execrows
+DELETE FROM assignments
+WHERE id = ?1 AND source <> 'canvas';
+
+-- name: ListAssignmentsByDueDate :many
+SELECT * FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL
+        OR (?10 IS NULL AND due_date IS NULL AND id > ?9)
+        OR (?10 IS NOT NULL AND (due_date IS NULL OR due_date > ?10 OR (due_date = ?10 AND id > ?9))))
+ORDER BY due_date NULLS LAST, id
+LIMIT ?11;
+
+-- name: ListCourseAssignmentsByDueDate :many
+SELECT * FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND course_id = ?2
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL
+        OR (?10 IS NULL AND due_date IS NULL AND id > ?9)
+        OR (?10 IS NOT NULL AND (due_date IS NULL OR due_date > ?10 OR (due_date = ?10 AND id > ?9))))
+ORDER BY due_date NULLS LAST, id
+LIMIT ?11;
+
+-- name: ListAssignmentsByDifficulty :many
+SELECT * FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL
+        OR (?10 IS NULL AND difficulty IS NULL AND id > ?9)
+        OR (?10 IS NOT NULL AND (difficulty IS NULL OR difficulty < ?10 OR (difficulty = ?10 AND id > ?9))))
+ORDER BY difficulty DESC NULLS LAST, id
+LIMIT ?11;
+
+-- name: ListAssignmentsByName :many
+SELECT * FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL OR name > ?10 OR (name = ?10 AND id > ?9))
+ORDER BY name, id
+LIMIT ?11;
+
+-- name: CountAssignments :one
+SELECT COUNT(*) FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8);
+
+-- name: CountCourseAssignments :one
+SELECT COUNT(*) FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND course_id = ?2
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8);
+
+-- name: UpsertSyllabus :one
+INSERT INTO syllabi (course_id, filename, content, uploaded_at)
+VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT(course_id, filename) DO UPDATE SET
+    content = excluded.content,
+    uploaded_at = excluded.uploaded_at
+RETURNING *;
+
+-- name: Search :many
+-- Matches are marked with char(2) and char(3) rather than HTML tags, so the snippets
+-- can be escaped before they're turned into <mark>s, see store.HighlightSnippet
+SELECT kind, id, course_id, course_name, title, snippet, rank FROM (
+    SELECT
+        'assignment' AS kind,
+        a.id,
+        a.course_id,
+        c.name AS course_name,
+        a.name AS title,
+        snippet(assignments_fts, -1, char(2), char(3), '...', 16) AS snippet,
+        -bm25(assignments_fts, 10.0, 1.0) AS rank
+    FROM assignments_fts
+    JOIN assignments a ON a.id = assignments_fts.rowid
+    JOIN courses c ON c.id = a.course_id
+    WHERE assignments_fts MATCH ?1
+        AND (?2 IS NULL OR a.course_id IN (SELECT value FROM json_each(?2)))
+    UNION ALL
+    SELECT
+        'syllabus' AS kind,
+        s.id,
+        s.course_id,
+        c.name AS course_name,
+        s.filename AS title,
+        snippet(syllabi_fts, -1, char(2), char(3), '...', 16) AS snippet,
+        -bm25(syllabi_fts, 10.0, 1.0) AS rank
+    FROM syllabi_fts
+    JOIN syllabi s ON s.id = syllabi_fts.rowid
+    JOIN courses c ON c.id = s.course_id
+    WHERE syllabi_fts MATCH ?1
+        AND (?2 IS NULL OR s.course_id IN (SELECT value FROM json_each(?2)))
+)
+ORDER BY rank DESC, kind, id
+LIMIT ?3;
+
+-- name: HasSearchIndex :one
+-- The FTS5 indexes only exist when the migrations ran with FTS5, see 0004_search
+SELECT EXISTS (
+    SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'assignments_fts'
+) AS has_index;
+
+-- name: SearchLike :many
+-- Search for a database migrated without FTS5. ?1 is a LIKE pattern made of the words
+-- searched for (%lab%3%), results whose title matches come first and the snippet is cut
+-- out of body, see store.likeSnippet.
+SELECT kind, id, course_id, course_name, title, body, rank FROM (
+    SELECT
+        'assignment' AS kind,
+        a.id,
+        a.course_id,
+        c.name AS course_name,
+        a.name AS title,
+        COALESCE(a.description_text, '') AS body,
+        CAST(a.name LIKE ?1 AS REAL) AS rank
+    FROM assignments a
+    JOIN courses c ON c.id = a.course_id
+    WHERE (a.name LIKE ?1 OR a.description_text LIKE ?1)
+        AND (?2 IS NULL OR a.course_id IN (SELECT value FROM json_each(?2)))
+    UNION ALL
+    SELECT
+        'syllabus' AS kind,
+        s.id,
+        s.course_id,
+        c.name AS course_name,
+        s.filename AS title,
+        s.content AS body,
+        CAST(s.filename LIKE ?1 AS REAL) AS rank
+    FROM syllabi s
+    JOIN courses c ON c.id = s.course_id
+    WHERE (s.filename LIKE ?1 OR s.content LIKE ?1)
+        AND (?2 IS NULL OR s.course_id IN (SELECT value FROM json_each(?2)))
+)
+ORDER BY rank DESC, kind, id
+LIMIT ?3;
+
+-- name: ListAssignmentsWithoutDescriptionText :many
+SELECT id, description FROM assignments
+WHERE description IS NOT NULL AND description_text IS NULL;
+
+-- name: UpdateAssignmentDescriptionText :exec
+UPDATE assignments
+SET description_text = ?2
+WHERE id = ?1;
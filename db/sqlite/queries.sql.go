@@ -8,8 +8,200 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
+const countAssignments = `-- name: CountAssignments :one
+SELECT COUNT(*) FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+`
+
+type CountAssignmentsParams struct {
+	IncludeSubmitted bool          `json:"include_submitted"`
+	CourseID         sql.NullInt64 `json:"course_id"`
+	DueAfter         sql.NullTime  `json:"due_after"`
+	DueBefore        sql.NullTime  `json:"due_before"`
+	Overdue          sql.NullBool  `json:"overdue"`
+	Now              sql.NullTime  `json:"now"`
+	MinDifficulty    sql.NullInt64 `json:"min_difficulty"`
+	HasDueDate       sql.NullBool  `json:"has_due_date"`
+}
+
+func (q *Queries) CountAssignments(ctx context.Context, arg CountAssignmentsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countAssignments,
+		arg.IncludeSubmitted,
+		arg.CourseID,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.Overdue,
+		arg.Now,
+		arg.MinDifficulty,
+		arg.HasDueDate,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCourseAssignments = `-- name: CountCourseAssignments :one
+SELECT COUNT(*) FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND course_id = ?2
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+`
+
+type CountCourseAssignmentsParams struct {
+	IncludeSubmitted bool          `json:"include_submitted"`
+	CourseID         int64         `json:"course_id"`
+	DueAfter         sql.NullTime  `json:"due_after"`
+	DueBefore        sql.NullTime  `json:"due_before"`
+	Overdue          sql.NullBool  `json:"overdue"`
+	Now              sql.NullTime  `json:"now"`
+	MinDifficulty    sql.NullInt64 `json:"min_difficulty"`
+	HasDueDate       sql.NullBool  `json:"has_due_date"`
+}
+
+func (q *Queries) CountCourseAssignments(ctx context.Context, arg CountCourseAssignmentsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countCourseAssignments,
+		arg.IncludeSubmitted,
+		arg.CourseID,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.Overdue,
+		arg.Now,
+		arg.MinDifficulty,
+		arg.HasDueDate,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createAssignmentEvent = `-- name: CreateAssignmentEvent :exec
+INSERT INTO assignment_events (assignment_id, course_id, event_type, old_value, new_value, created_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6)
+`
+
+type CreateAssignmentEventParams struct {
+	AssignmentID int64          `json:"assignment_id"`
+	CourseID     int64          `json:"course_id"`
+	EventType    string         `json:"event_type"`
+	OldValue     sql.NullString `json:"old_value"`
+	NewValue     sql.NullString `json:"new_value"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+func (q *Queries) CreateAssignmentEvent(ctx context.Context, arg CreateAssignmentEventParams) error {
+	_, err := q.db.ExecContext(ctx, createAssignmentEvent,
+		arg.AssignmentID,
+		arg.CourseID,
+		arg.EventType,
+		arg.OldValue,
+		arg.NewValue,
+		arg.CreatedAt,
+	)
+	return err
+}
+
+const createSyncRun = `-- name: CreateSyncRun :one
+INSERT INTO sync_runs (source, started_at)
+VALUES (?1, ?2)
+RETURNING id, source, started_at, finished_at, courses_synced, assignments_synced, error
+`
+
+type CreateSyncRunParams struct {
+	Source    string    `json:"source"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func (q *Queries) CreateSyncRun(ctx context.Context, arg CreateSyncRunParams) (SyncRun, error) {
+	row := q.db.QueryRowContext(ctx, createSyncRun, arg.Source, arg.StartedAt)
+	var i SyncRun
+	err := row.Scan(
+		&i.ID,
+		&i.Source,
+		&i.StartedAt,
+		&i.FinishedAt,
+		&i.CoursesSynced,
+		&i.AssignmentsSynced,
+		&i.Error,
+	)
+	return i, err
+}
+
+const createTask = `-- name: CreateTask :one
+INSERT INTO assignments (id, course_id, name, due_date, difficulty, length, description, description_text, source)
+VALUES (
+    (SELECT last_id - 1 FROM task_sequence WHERE id = 1),
+    ?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7
+)
+RETURNING id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text
+`
+
+type CreateTaskParams struct {
+	CourseID    int64          `json:"course_id"`
+	Name        string         `json:"name"`
+	DueDate     sql.NullTime   `json:"due_date"`
+	Difficulty  sql.NullInt64  `json:"difficulty"`
+	Length      sql.NullInt64  `json:"length"`
+	Description sql.NullString `json:"description"`
+	Source      string         `json:"source"`
+}
+
+func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (Assignment, error) {
+	row := q.db.QueryRowContext(ctx, createTask,
+		arg.CourseID,
+		arg.Name,
+		arg.DueDate,
+		arg.Difficulty,
+		arg.Length,
+		arg.Description,
+		arg.Source,
+	)
+	var i Assignment
+	err := row.Scan(
+		&i.ID,
+		&i.CourseID,
+		&i.Name,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.Difficulty,
+		&i.Length,
+		&i.PointsPossible,
+		&i.SubmissionTypes,
+		&i.UnlockAt,
+		&i.LockAt,
+		&i.HtmlUrl,
+		&i.Published,
+		&i.AssignmentGroupID,
+		&i.Description,
+		&i.Version,
+		&i.Source,
+		&i.DescriptionText,
+	)
+	return i, err
+}
+
 const deleteAssignment = `-- name: DeleteAssignment :exec
 DELETE FROM assignments
 WHERE course_id = ?1 AND id = ?2
@@ -25,6 +217,16 @@ func (q *Queries) DeleteAssignment(ctx context.Context, arg DeleteAssignmentPara
 	return err
 }
 
+const deleteAssignmentOverride = `-- name: DeleteAssignmentOverride :exec
+DELETE FROM assignment_overrides
+WHERE assignment_id = ?1
+`
+
+func (q *Queries) DeleteAssignmentOverride(ctx context.Context, assignmentID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteAssignmentOverride, assignmentID)
+	return err
+}
+
 const deleteAssignmentsByCourse = `-- name: DeleteAssignmentsByCourse :exec
 DELETE FROM assignments 
 WHERE course_id = ?1
@@ -45,8 +247,50 @@ func (q *Queries) DeleteCourse(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteTask = `-- name: DeleteTask :execrows
+DELETE FROM assignments
+WHERE id = ?1 AND source <> 'canvas'
+`
+
+func (q *Queries) DeleteTask(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTask, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const finishSyncRun = `-- name: FinishSyncRun :exec
+UPDATE sync_runs
+SET
+    finished_at = ?2,
+    courses_synced = ?3,
+    assignments_synced = ?4,
+    error = ?5
+WHERE id = ?1
+`
+
+type FinishSyncRunParams struct {
+	ID                int64          `json:"id"`
+	FinishedAt        sql.NullTime   `json:"finished_at"`
+	CoursesSynced     int64          `json:"courses_synced"`
+	AssignmentsSynced int64          `json:"assignments_synced"`
+	Error             sql.NullString `json:"error"`
+}
+
+func (q *Queries) FinishSyncRun(ctx context.Context, arg FinishSyncRunParams) error {
+	_, err := q.db.ExecContext(ctx, finishSyncRun,
+		arg.ID,
+		arg.FinishedAt,
+		arg.CoursesSynced,
+		arg.AssignmentsSynced,
+		arg.Error,
+	)
+	return err
+}
+
 const getAssignment = `-- name: GetAssignment :one
-SELECT id, course_id, name, due_date, created_at, difficulty, length FROM assignments
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
 WHERE id = ?1 and course_id = ?2
 `
 
@@ -66,6 +310,17 @@ func (q *Queries) GetAssignment(ctx context.Context, arg GetAssignmentParams) (A
 		&i.CreatedAt,
 		&i.Difficulty,
 		&i.Length,
+		&i.PointsPossible,
+		&i.SubmissionTypes,
+		&i.UnlockAt,
+		&i.LockAt,
+		&i.HtmlUrl,
+		&i.Published,
+		&i.AssignmentGroupID,
+		&i.Description,
+		&i.Version,
+		&i.Source,
+		&i.DescriptionText,
 	)
 	return i, err
 }
@@ -114,8 +369,106 @@ func (q *Queries) GetAssignmentCountsByCourse(ctx context.Context) ([]GetAssignm
 	return items, nil
 }
 
+const getAssignmentOverride = `-- name: GetAssignmentOverride :one
+SELECT assignment_id, name, due_date, difficulty, length, canvas_name, canvas_due_date, updated_at FROM assignment_overrides
+WHERE assignment_id = ?1
+`
+
+func (q *Queries) GetAssignmentOverride(ctx context.Context, assignmentID int64) (AssignmentOverride, error) {
+	row := q.db.QueryRowContext(ctx, getAssignmentOverride, assignmentID)
+	var i AssignmentOverride
+	err := row.Scan(
+		&i.AssignmentID,
+		&i.Name,
+		&i.DueDate,
+		&i.Difficulty,
+		&i.Length,
+		&i.CanvasName,
+		&i.CanvasDueDate,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getCourse = `-- name: GetCourse :one
+SELECT id, name, created_at, term_id, term_name, start_at, end_at FROM courses
+WHERE id = ?1
+`
+
+func (q *Queries) GetCourse(ctx context.Context, id int64) (Course, error) {
+	row := q.db.QueryRowContext(ctx, getCourse, id)
+	var i Course
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.TermID,
+		&i.TermName,
+		&i.StartAt,
+		&i.EndAt,
+	)
+	return i, err
+}
+
+const getCourseSyncState = `-- name: GetCourseSyncState :one
+SELECT course_id, etag, last_synced_at FROM course_sync_state
+WHERE course_id = ?1
+`
+
+func (q *Queries) GetCourseSyncState(ctx context.Context, courseID int64) (CourseSyncState, error) {
+	row := q.db.QueryRowContext(ctx, getCourseSyncState, courseID)
+	var i CourseSyncState
+	err := row.Scan(&i.CourseID, &i.Etag, &i.LastSyncedAt)
+	return i, err
+}
+
+const getTask = `-- name: GetTask :one
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE id = ?1 AND source <> 'canvas'
+`
+
+func (q *Queries) GetTask(ctx context.Context, id int64) (Assignment, error) {
+	row := q.db.QueryRowContext(ctx, getTask, id)
+	var i Assignment
+	err := row.Scan(
+		&i.ID,
+		&i.CourseID,
+		&i.Name,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.Difficulty,
+		&i.Length,
+		&i.PointsPossible,
+		&i.SubmissionTypes,
+		&i.UnlockAt,
+		&i.LockAt,
+		&i.HtmlUrl,
+		&i.Published,
+		&i.AssignmentGroupID,
+		&i.Description,
+		&i.Version,
+		&i.Source,
+		&i.DescriptionText,
+	)
+	return i, err
+}
+
+const hasSearchIndex = `-- name: HasSearchIndex :one
+SELECT EXISTS (
+    SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'assignments_fts'
+) AS has_index
+`
+
+// The FTS5 indexes only exist when the migrations ran with FTS5, see 0004_search
+func (q *Queries) HasSearchIndex(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, hasSearchIndex)
+	var has_index int64
+	err := row.Scan(&has_index)
+	return has_index, err
+}
+
 const listAllAssignments = `-- name: ListAllAssignments :many
-SELECT id, course_id, name, due_date, created_at, difficulty, length FROM assignments
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
 `
 
 func (q *Queries) ListAllAssignments(ctx context.Context) ([]Assignment, error) {
@@ -135,6 +488,17 @@ func (q *Queries) ListAllAssignments(ctx context.Context) ([]Assignment, error)
 			&i.CreatedAt,
 			&i.Difficulty,
 			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
 		); err != nil {
 			return nil, err
 		}
@@ -150,7 +514,7 @@ func (q *Queries) ListAllAssignments(ctx context.Context) ([]Assignment, error)
 }
 
 const listAllCourses = `-- name: ListAllCourses :many
-SELECT id, name, created_at FROM courses
+SELECT id, name, created_at, term_id, term_name, start_at, end_at FROM courses
 `
 
 func (q *Queries) ListAllCourses(ctx context.Context) ([]Course, error) {
@@ -162,7 +526,92 @@ func (q *Queries) ListAllCourses(ctx context.Context) ([]Course, error) {
 	var items []Course
 	for rows.Next() {
 		var i Course
-		if err := rows.Scan(&i.ID, &i.Name, &i.CreatedAt); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CreatedAt,
+			&i.TermID,
+			&i.TermName,
+			&i.StartAt,
+			&i.EndAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAssignmentEventsSince = `-- name: ListAssignmentEventsSince :many
+SELECT id, assignment_id, course_id, event_type, old_value, new_value, created_at FROM assignment_events
+WHERE created_at >= ?1
+ORDER BY created_at, id
+`
+
+func (q *Queries) ListAssignmentEventsSince(ctx context.Context, createdAt time.Time) ([]AssignmentEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listAssignmentEventsSince, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AssignmentEvent
+	for rows.Next() {
+		var i AssignmentEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.AssignmentID,
+			&i.CourseID,
+			&i.EventType,
+			&i.OldValue,
+			&i.NewValue,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAssignmentOverridesByCourse = `-- name: ListAssignmentOverridesByCourse :many
+SELECT assignment_id, name, due_date, difficulty, length, canvas_name, canvas_due_date, updated_at FROM assignment_overrides
+WHERE assignment_id IN (
+    SELECT id FROM assignments
+    WHERE course_id = ?1
+)
+`
+
+func (q *Queries) ListAssignmentOverridesByCourse(ctx context.Context, courseID int64) ([]AssignmentOverride, error) {
+	rows, err := q.db.QueryContext(ctx, listAssignmentOverridesByCourse, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AssignmentOverride
+	for rows.Next() {
+		var i AssignmentOverride
+		if err := rows.Scan(
+			&i.AssignmentID,
+			&i.Name,
+			&i.DueDate,
+			&i.Difficulty,
+			&i.Length,
+			&i.CanvasName,
+			&i.CanvasDueDate,
+			&i.UpdatedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -212,64 +661,1094 @@ func (q *Queries) ListAssignmentsByCourse(ctx context.Context, courseID int64) (
 	return items, nil
 }
 
-const updateAssignment = `-- name: UpdateAssignment :exec
-UPDATE assignments
-SET 
-    name = ?2,
-    due_date = ?3,
-    difficulty = ?4,
-    length = ?5
-WHERE id = ?1
+const listAssignmentsByDifficulty = `-- name: ListAssignmentsByDifficulty :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL
+        OR (?10 IS NULL AND difficulty IS NULL AND id > ?9)
+        OR (?10 IS NOT NULL AND (difficulty IS NULL OR difficulty < ?10 OR (difficulty = ?10 AND id > ?9))))
+ORDER BY difficulty DESC NULLS LAST, id
+LIMIT ?11
 `
 
-type UpdateAssignmentParams struct {
-	ID         int64         `json:"id"`
-	Name       string        `json:"name"`
-	DueDate    sql.NullTime  `json:"due_date"`
-	Difficulty sql.NullInt64 `json:"difficulty"`
-	Length     sql.NullInt64 `json:"length"`
+type ListAssignmentsByDifficultyParams struct {
+	IncludeSubmitted bool          `json:"include_submitted"`
+	CourseID         sql.NullInt64 `json:"course_id"`
+	DueAfter         sql.NullTime  `json:"due_after"`
+	DueBefore        sql.NullTime  `json:"due_before"`
+	Overdue          sql.NullBool  `json:"overdue"`
+	Now              sql.NullTime  `json:"now"`
+	MinDifficulty    sql.NullInt64 `json:"min_difficulty"`
+	HasDueDate       sql.NullBool  `json:"has_due_date"`
+	AfterID          sql.NullInt64 `json:"after_id"`
+	AfterDifficulty  sql.NullInt64 `json:"after_difficulty"`
+	Limit            int64         `json:"limit"`
 }
 
-func (q *Queries) UpdateAssignment(ctx context.Context, arg UpdateAssignmentParams) error {
-	_, err := q.db.ExecContext(ctx, updateAssignment,
-		arg.ID,
-		arg.Name,
-		arg.DueDate,
-		arg.Difficulty,
-		arg.Length,
+func (q *Queries) ListAssignmentsByDifficulty(ctx context.Context, arg ListAssignmentsByDifficultyParams) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listAssignmentsByDifficulty,
+		arg.IncludeSubmitted,
+		arg.CourseID,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.Overdue,
+		arg.Now,
+		arg.MinDifficulty,
+		arg.HasDueDate,
+		arg.AfterID,
+		arg.AfterDifficulty,
+		arg.Limit,
 	)
-	return err
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const upsertAssignment = `-- name: UpsertAssignment :one
-INSERT INTO assignments (id, course_id, name, due_date, difficulty, length)
-VALUES (?1, ?2, ?3, ?4, ?5, ?6)
-ON CONFLICT(id) DO UPDATE SET 
-    course_id = excluded.course_id,
-    name = excluded.name,
-    due_date = excluded.due_date,
-    difficulty = excluded.difficulty,
-    length = excluded.length
-RETURNING id, course_id, name, due_date, created_at, difficulty, length
+const listAssignmentsByDueDate = `-- name: ListAssignmentsByDueDate :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL
+        OR (?10 IS NULL AND due_date IS NULL AND id > ?9)
+        OR (?10 IS NOT NULL AND (due_date IS NULL OR due_date > ?10 OR (due_date = ?10 AND id > ?9))))
+ORDER BY due_date NULLS LAST, id
+LIMIT ?11
 `
 
-type UpsertAssignmentParams struct {
-	ID         int64         `json:"id"`
-	CourseID   int64         `json:"course_id"`
-	Name       string        `json:"name"`
-	DueDate    sql.NullTime  `json:"due_date"`
-	Difficulty sql.NullInt64 `json:"difficulty"`
-	Length     sql.NullInt64 `json:"length"`
+type ListAssignmentsByDueDateParams struct {
+	IncludeSubmitted bool          `json:"include_submitted"`
+	CourseID         sql.NullInt64 `json:"course_id"`
+	DueAfter         sql.NullTime  `json:"due_after"`
+	DueBefore        sql.NullTime  `json:"due_before"`
+	Overdue          sql.NullBool  `json:"overdue"`
+	Now              sql.NullTime  `json:"now"`
+	MinDifficulty    sql.NullInt64 `json:"min_difficulty"`
+	HasDueDate       sql.NullBool  `json:"has_due_date"`
+	AfterID          sql.NullInt64 `json:"after_id"`
+	AfterDueDate     sql.NullTime  `json:"after_due_date"`
+	Limit            int64         `json:"limit"`
 }
 
-func (q *Queries) UpsertAssignment(ctx context.Context, arg UpsertAssignmentParams) (Assignment, error) {
-	row := q.db.QueryRowContext(ctx, upsertAssignment,
-		arg.ID,
+func (q *Queries) ListAssignmentsByDueDate(ctx context.Context, arg ListAssignmentsByDueDateParams) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listAssignmentsByDueDate,
+		arg.IncludeSubmitted,
 		arg.CourseID,
-		arg.Name,
-		arg.DueDate,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.Overdue,
+		arg.Now,
+		arg.MinDifficulty,
+		arg.HasDueDate,
+		arg.AfterID,
+		arg.AfterDueDate,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAssignmentsByName = `-- name: ListAssignmentsByName :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND (?2 IS NULL OR course_id = ?2)
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL OR name > ?10 OR (name = ?10 AND id > ?9))
+ORDER BY name, id
+LIMIT ?11
+`
+
+type ListAssignmentsByNameParams struct {
+	IncludeSubmitted bool          `json:"include_submitted"`
+	CourseID         sql.NullInt64 `json:"course_id"`
+	DueAfter         sql.NullTime  `json:"due_after"`
+	DueBefore        sql.NullTime  `json:"due_before"`
+	Overdue          sql.NullBool  `json:"overdue"`
+	Now              sql.NullTime  `json:"now"`
+	MinDifficulty    sql.NullInt64 `json:"min_difficulty"`
+	HasDueDate       sql.NullBool  `json:"has_due_date"`
+	AfterID          sql.NullInt64 `json:"after_id"`
+	AfterName        string        `json:"after_name"`
+	Limit            int64         `json:"limit"`
+}
+
+func (q *Queries) ListAssignmentsByName(ctx context.Context, arg ListAssignmentsByNameParams) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listAssignmentsByName,
+		arg.IncludeSubmitted,
+		arg.CourseID,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.Overdue,
+		arg.Now,
+		arg.MinDifficulty,
+		arg.HasDueDate,
+		arg.AfterID,
+		arg.AfterName,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAssignmentsWithoutDescriptionText = `-- name: ListAssignmentsWithoutDescriptionText :many
+SELECT id, description FROM assignments
+WHERE description IS NOT NULL AND description_text IS NULL
+`
+
+type ListAssignmentsWithoutDescriptionTextRow struct {
+	ID          int64          `json:"id"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) ListAssignmentsWithoutDescriptionText(ctx context.Context) ([]ListAssignmentsWithoutDescriptionTextRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAssignmentsWithoutDescriptionText)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListAssignmentsWithoutDescriptionTextRow
+	for rows.Next() {
+		var i ListAssignmentsWithoutDescriptionTextRow
+		if err := rows.Scan(&i.ID, &i.Description); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCourseAssignments = `-- name: ListCourseAssignments :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE course_id = ?1
+`
+
+func (q *Queries) ListCourseAssignments(ctx context.Context, courseID int64) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listCourseAssignments, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCourseAssignmentsByDueDate = `-- name: ListCourseAssignmentsByDueDate :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE (?1 OR id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    ))
+    AND course_id = ?2
+    AND (?3 IS NULL OR due_date >= ?3)
+    AND (?4 IS NULL OR due_date < ?4)
+    AND (?5 IS NULL
+        OR (?5 AND due_date < ?6)
+        OR (NOT ?5 AND (due_date IS NULL OR due_date >= ?6)))
+    AND (?7 IS NULL OR difficulty >= ?7)
+    AND (?8 IS NULL OR (due_date IS NOT NULL) = ?8)
+    AND (?9 IS NULL
+        OR (?10 IS NULL AND due_date IS NULL AND id > ?9)
+        OR (?10 IS NOT NULL AND (due_date IS NULL OR due_date > ?10 OR (due_date = ?10 AND id > ?9))))
+ORDER BY due_date NULLS LAST, id
+LIMIT ?11
+`
+
+type ListCourseAssignmentsByDueDateParams struct {
+	IncludeSubmitted bool          `json:"include_submitted"`
+	CourseID         int64         `json:"course_id"`
+	DueAfter         sql.NullTime  `json:"due_after"`
+	DueBefore        sql.NullTime  `json:"due_before"`
+	Overdue          sql.NullBool  `json:"overdue"`
+	Now              sql.NullTime  `json:"now"`
+	MinDifficulty    sql.NullInt64 `json:"min_difficulty"`
+	HasDueDate       sql.NullBool  `json:"has_due_date"`
+	AfterID          sql.NullInt64 `json:"after_id"`
+	AfterDueDate     sql.NullTime  `json:"after_due_date"`
+	Limit            int64         `json:"limit"`
+}
+
+func (q *Queries) ListCourseAssignmentsByDueDate(ctx context.Context, arg ListCourseAssignmentsByDueDateParams) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listCourseAssignmentsByDueDate,
+		arg.IncludeSubmitted,
+		arg.CourseID,
+		arg.DueAfter,
+		arg.DueBefore,
+		arg.Overdue,
+		arg.Now,
+		arg.MinDifficulty,
+		arg.HasDueDate,
+		arg.AfterID,
+		arg.AfterDueDate,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingAssignments = `-- name: ListPendingAssignments :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE id NOT IN (
+    SELECT assignment_id FROM submissions
+    WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+)
+`
+
+func (q *Queries) ListPendingAssignments(ctx context.Context) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingAssignments)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSyncRuns = `-- name: ListSyncRuns :many
+SELECT id, source, started_at, finished_at, courses_synced, assignments_synced, error FROM sync_runs
+ORDER BY started_at DESC
+LIMIT ?1
+`
+
+func (q *Queries) ListSyncRuns(ctx context.Context, limit int64) ([]SyncRun, error) {
+	rows, err := q.db.QueryContext(ctx, listSyncRuns, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SyncRun
+	for rows.Next() {
+		var i SyncRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.Source,
+			&i.StartedAt,
+			&i.FinishedAt,
+			&i.CoursesSynced,
+			&i.AssignmentsSynced,
+			&i.Error,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTasks = `-- name: ListTasks :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE source <> 'canvas'
+ORDER BY due_date, id
+`
+
+func (q *Queries) ListTasks(ctx context.Context) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listTasks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUpcomingAssignments = `-- name: ListUpcomingAssignments :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE due_date >= ?1
+ORDER BY due_date
+LIMIT ?2
+`
+
+type ListUpcomingAssignmentsParams struct {
+	DueDate sql.NullTime `json:"due_date"`
+	Limit   int64        `json:"limit"`
+}
+
+func (q *Queries) ListUpcomingAssignments(ctx context.Context, arg ListUpcomingAssignmentsParams) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listUpcomingAssignments, arg.DueDate, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUpcomingPendingAssignments = `-- name: ListUpcomingPendingAssignments :many
+SELECT id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text FROM assignments
+WHERE due_date >= ?1
+    AND id NOT IN (
+        SELECT assignment_id FROM submissions
+        WHERE excused OR workflow_state IN ('submitted', 'graded', 'pending_review')
+    )
+ORDER BY due_date
+LIMIT ?2
+`
+
+type ListUpcomingPendingAssignmentsParams struct {
+	DueDate sql.NullTime `json:"due_date"`
+	Limit   int64        `json:"limit"`
+}
+
+func (q *Queries) ListUpcomingPendingAssignments(ctx context.Context, arg ListUpcomingPendingAssignmentsParams) ([]Assignment, error) {
+	rows, err := q.db.QueryContext(ctx, listUpcomingPendingAssignments, arg.DueDate, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Assignment
+	for rows.Next() {
+		var i Assignment
+		if err := rows.Scan(
+			&i.ID,
+			&i.CourseID,
+			&i.Name,
+			&i.DueDate,
+			&i.CreatedAt,
+			&i.Difficulty,
+			&i.Length,
+			&i.PointsPossible,
+			&i.SubmissionTypes,
+			&i.UnlockAt,
+			&i.LockAt,
+			&i.HtmlUrl,
+			&i.Published,
+			&i.AssignmentGroupID,
+			&i.Description,
+			&i.Version,
+			&i.Source,
+			&i.DescriptionText,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const search = `-- name: Search :many
+SELECT kind, id, course_id, course_name, title, snippet, rank FROM (
+    SELECT
+        'assignment' AS kind,
+        a.id,
+        a.course_id,
+        c.name AS course_name,
+        a.name AS title,
+        snippet(assignments_fts, -1, char(2), char(3), '...', 16) AS snippet,
+        -bm25(assignments_fts, 10.0, 1.0) AS rank
+    FROM assignments_fts
+    JOIN assignments a ON a.id = assignments_fts.rowid
+    JOIN courses c ON c.id = a.course_id
+    WHERE assignments_fts MATCH ?1
+        AND (?2 IS NULL OR a.course_id IN (SELECT value FROM json_each(?2)))
+    UNION ALL
+    SELECT
+        'syllabus' AS kind,
+        s.id,
+        s.course_id,
+        c.name AS course_name,
+        s.filename AS title,
+        snippet(syllabi_fts, -1, char(2), char(3), '...', 16) AS snippet,
+        -bm25(syllabi_fts, 10.0, 1.0) AS rank
+    FROM syllabi_fts
+    JOIN syllabi s ON s.id = syllabi_fts.rowid
+    JOIN courses c ON c.id = s.course_id
+    WHERE syllabi_fts MATCH ?1
+        AND (?2 IS NULL OR s.course_id IN (SELECT value FROM json_each(?2)))
+)
+ORDER BY rank DESC, kind, id
+LIMIT ?3
+`
+
+type SearchParams struct {
+	Match     string         `json:"match"`
+	CourseIDs sql.NullString `json:"course_ids"`
+	Limit     int64          `json:"limit"`
+}
+
+type SearchRow struct {
+	Kind       string  `json:"kind"`
+	ID         int64   `json:"id"`
+	CourseID   int64   `json:"course_id"`
+	CourseName string  `json:"course_name"`
+	Title      string  `json:"title"`
+	Snippet    string  `json:"snippet"`
+	Rank       float64 `json:"rank"`
+}
+
+// Matches are marked with char(2) and char(3) rather than HTML tags, so the snippets
+// can be escaped before they're turned into <mark>s, see store.HighlightSnippet
+func (q *Queries) Search(ctx context.Context, arg SearchParams) ([]SearchRow, error) {
+	rows, err := q.db.QueryContext(ctx, search, arg.Match, arg.CourseIDs, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchRow
+	for rows.Next() {
+		var i SearchRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.ID,
+			&i.CourseID,
+			&i.CourseName,
+			&i.Title,
+			&i.Snippet,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchLike = `-- name: SearchLike :many
+SELECT kind, id, course_id, course_name, title, body, rank FROM (
+    SELECT
+        'assignment' AS kind,
+        a.id,
+        a.course_id,
+        c.name AS course_name,
+        a.name AS title,
+        COALESCE(a.description_text, '') AS body,
+        CAST(a.name LIKE ?1 AS REAL) AS rank
+    FROM assignments a
+    JOIN courses c ON c.id = a.course_id
+    WHERE (a.name LIKE ?1 OR a.description_text LIKE ?1)
+        AND (?2 IS NULL OR a.course_id IN (SELECT value FROM json_each(?2)))
+    UNION ALL
+    SELECT
+        'syllabus' AS kind,
+        s.id,
+        s.course_id,
+        c.name AS course_name,
+        s.filename AS title,
+        s.content AS body,
+        CAST(s.filename LIKE ?1 AS REAL) AS rank
+    FROM syllabi s
+    JOIN courses c ON c.id = s.course_id
+    WHERE (s.filename LIKE ?1 OR s.content LIKE ?1)
+        AND (?2 IS NULL OR s.course_id IN (SELECT value FROM json_each(?2)))
+)
+ORDER BY rank DESC, kind, id
+LIMIT ?3
+`
+
+type SearchLikeParams struct {
+	Pattern   string         `json:"pattern"`
+	CourseIDs sql.NullString `json:"course_ids"`
+	Limit     int64          `json:"limit"`
+}
+
+type SearchLikeRow struct {
+	Kind       string  `json:"kind"`
+	ID         int64   `json:"id"`
+	CourseID   int64   `json:"course_id"`
+	CourseName string  `json:"course_name"`
+	Title      string  `json:"title"`
+	Body       string  `json:"body"`
+	Rank       float64 `json:"rank"`
+}
+
+// Search for a database migrated without FTS5. ?1 is a LIKE pattern made of the words
+// searched for (%lab%3%), results whose title matches come first and the snippet is cut
+// out of body, see store.likeSnippet.
+func (q *Queries) SearchLike(ctx context.Context, arg SearchLikeParams) ([]SearchLikeRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchLike, arg.Pattern, arg.CourseIDs, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchLikeRow
+	for rows.Next() {
+		var i SearchLikeRow
+		if err := rows.Scan(
+			&i.Kind,
+			&i.ID,
+			&i.CourseID,
+			&i.CourseName,
+			&i.Title,
+			&i.Body,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateAssignment = `-- name: UpdateAssignment :exec
+UPDATE assignments
+SET 
+    name = ?2,
+    due_date = ?3,
+    difficulty = ?4,
+    length = ?5,
+    version = version + 1
+WHERE id = ?1
+`
+
+type UpdateAssignmentParams struct {
+	ID         int64         `json:"id"`
+	Name       string        `json:"name"`
+	DueDate    sql.NullTime  `json:"due_date"`
+	Difficulty sql.NullInt64 `json:"difficulty"`
+	Length     sql.NullInt64 `json:"length"`
+}
+
+func (q *Queries) UpdateAssignment(ctx context.Context, arg UpdateAssignmentParams) error {
+	_, err := q.db.ExecContext(ctx, updateAssignment,
+		arg.ID,
+		arg.Name,
+		arg.DueDate,
+		arg.Difficulty,
+		arg.Length,
+	)
+	return err
+}
+
+const updateAssignmentDescriptionText = `-- name: UpdateAssignmentDescriptionText :exec
+UPDATE assignments
+SET description_text = ?2
+WHERE id = ?1
+`
+
+type UpdateAssignmentDescriptionTextParams struct {
+	ID              int64          `json:"id"`
+	DescriptionText sql.NullString `json:"description_text"`
+}
+
+func (q *Queries) UpdateAssignmentDescriptionText(ctx context.Context, arg UpdateAssignmentDescriptionTextParams) error {
+	_, err := q.db.ExecContext(ctx, updateAssignmentDescriptionText, arg.ID, arg.DescriptionText)
+	return err
+}
+
+const updateAssignmentIfVersion = `-- name: UpdateAssignmentIfVersion :one
+UPDATE assignments
+SET
+    name = ?3,
+    due_date = ?4,
+    difficulty = ?5,
+    length = ?6,
+    version = version + 1
+WHERE course_id = ?1 AND id = ?2 AND version = ?7
+RETURNING id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text
+`
+
+type UpdateAssignmentIfVersionParams struct {
+	CourseID   int64         `json:"course_id"`
+	ID         int64         `json:"id"`
+	Name       string        `json:"name"`
+	DueDate    sql.NullTime  `json:"due_date"`
+	Difficulty sql.NullInt64 `json:"difficulty"`
+	Length     sql.NullInt64 `json:"length"`
+	Version    int64         `json:"version"`
+}
+
+func (q *Queries) UpdateAssignmentIfVersion(ctx context.Context, arg UpdateAssignmentIfVersionParams) (Assignment, error) {
+	row := q.db.QueryRowContext(ctx, updateAssignmentIfVersion,
+		arg.CourseID,
+		arg.ID,
+		arg.Name,
+		arg.DueDate,
+		arg.Difficulty,
+		arg.Length,
+		arg.Version,
+	)
+	var i Assignment
+	err := row.Scan(
+		&i.ID,
+		&i.CourseID,
+		&i.Name,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.Difficulty,
+		&i.Length,
+		&i.PointsPossible,
+		&i.SubmissionTypes,
+		&i.UnlockAt,
+		&i.LockAt,
+		&i.HtmlUrl,
+		&i.Published,
+		&i.AssignmentGroupID,
+		&i.Description,
+		&i.Version,
+		&i.Source,
+		&i.DescriptionText,
+	)
+	return i, err
+}
+
+const updateAssignmentOverrideCanvas = `-- name: UpdateAssignmentOverrideCanvas :exec
+UPDATE assignment_overrides
+SET
+    canvas_name = ?2,
+    canvas_due_date = ?3
+WHERE assignment_id = ?1
+`
+
+type UpdateAssignmentOverrideCanvasParams struct {
+	AssignmentID  int64        `json:"assignment_id"`
+	CanvasName    string       `json:"canvas_name"`
+	CanvasDueDate sql.NullTime `json:"canvas_due_date"`
+}
+
+func (q *Queries) UpdateAssignmentOverrideCanvas(ctx context.Context, arg UpdateAssignmentOverrideCanvasParams) error {
+	_, err := q.db.ExecContext(ctx, updateAssignmentOverrideCanvas, arg.AssignmentID, arg.CanvasName, arg.CanvasDueDate)
+	return err
+}
+
+const updateTaskIfVersion = `-- name: UpdateTaskIfVersion :one
+UPDATE assignments
+SET
+    course_id = ?2,
+    name = ?3,
+    due_date = ?4,
+    difficulty = ?5,
+    length = ?6,
+    description = ?7,
+    description_text = ?7,
+    version = version + 1
+WHERE id = ?1 AND version = ?8 AND source <> 'canvas'
+RETURNING id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text
+`
+
+type UpdateTaskIfVersionParams struct {
+	ID          int64          `json:"id"`
+	CourseID    int64          `json:"course_id"`
+	Name        string         `json:"name"`
+	DueDate     sql.NullTime   `json:"due_date"`
+	Difficulty  sql.NullInt64  `json:"difficulty"`
+	Length      sql.NullInt64  `json:"length"`
+	Description sql.NullString `json:"description"`
+	Version     int64          `json:"version"`
+}
+
+func (q *Queries) UpdateTaskIfVersion(ctx context.Context, arg UpdateTaskIfVersionParams) (Assignment, error) {
+	row := q.db.QueryRowContext(ctx, updateTaskIfVersion,
+		arg.ID,
+		arg.CourseID,
+		arg.Name,
+		arg.DueDate,
+		arg.Difficulty,
+		arg.Length,
+		arg.Description,
+		arg.Version,
+	)
+	var i Assignment
+	err := row.Scan(
+		&i.ID,
+		&i.CourseID,
+		&i.Name,
+		&i.DueDate,
+		&i.CreatedAt,
+		&i.Difficulty,
+		&i.Length,
+		&i.PointsPossible,
+		&i.SubmissionTypes,
+		&i.UnlockAt,
+		&i.LockAt,
+		&i.HtmlUrl,
+		&i.Published,
+		&i.AssignmentGroupID,
+		&i.Description,
+		&i.Version,
+		&i.Source,
+		&i.DescriptionText,
+	)
+	return i, err
+}
+
+const upsertAssignment = `-- name: UpsertAssignment :one
+INSERT INTO assignments (
+    id, course_id, name, due_date, difficulty, length, points_possible, submission_types,
+    unlock_at, lock_at, html_url, published, assignment_group_id, description, description_text
+)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15)
+ON CONFLICT(id) DO UPDATE SET 
+    course_id = excluded.course_id,
+    name = excluded.name,
+    due_date = excluded.due_date,
+    difficulty = excluded.difficulty,
+    length = excluded.length,
+    points_possible = excluded.points_possible,
+    submission_types = excluded.submission_types,
+    unlock_at = excluded.unlock_at,
+    lock_at = excluded.lock_at,
+    html_url = excluded.html_url,
+    published = excluded.published,
+    assignment_group_id = excluded.assignment_group_id,
+    description = excluded.description,
+    description_text = excluded.description_text,
+    version = assignments.version + 1
+RETURNING id, course_id, name, due_date, created_at, difficulty, length, points_possible, submission_types, unlock_at, lock_at, html_url, published, assignment_group_id, description, version, source, description_text
+`
+
+type UpsertAssignmentParams struct {
+	ID                int64           `json:"id"`
+	CourseID          int64           `json:"course_id"`
+	Name              string          `json:"name"`
+	DueDate           sql.NullTime    `json:"due_date"`
+	Difficulty        sql.NullInt64   `json:"difficulty"`
+	Length            sql.NullInt64   `json:"length"`
+	PointsPossible    sql.NullFloat64 `json:"points_possible"`
+	SubmissionTypes   sql.NullString  `json:"submission_types"`
+	UnlockAt          sql.NullTime    `json:"unlock_at"`
+	LockAt            sql.NullTime    `json:"lock_at"`
+	HtmlUrl           sql.NullString  `json:"html_url"`
+	Published         bool            `json:"published"`
+	AssignmentGroupID sql.NullInt64   `json:"assignment_group_id"`
+	Description       sql.NullString  `json:"description"`
+	DescriptionText   sql.NullString  `json:"description_text"`
+}
+
+func (q *Queries) UpsertAssignment(ctx context.Context, arg UpsertAssignmentParams) (Assignment, error) {
+	row := q.db.QueryRowContext(ctx, upsertAssignment,
+		arg.ID,
+		arg.CourseID,
+		arg.Name,
+		arg.DueDate,
 		arg.Difficulty,
 		arg.Length,
+		arg.PointsPossible,
+		arg.SubmissionTypes,
+		arg.UnlockAt,
+		arg.LockAt,
+		arg.HtmlUrl,
+		arg.Published,
+		arg.AssignmentGroupID,
+		arg.Description,
+		arg.DescriptionText,
 	)
 	var i Assignment
 	err := row.Scan(
@@ -280,27 +1759,187 @@ func (q *Queries) UpsertAssignment(ctx context.Context, arg UpsertAssignmentPara
 		&i.CreatedAt,
 		&i.Difficulty,
 		&i.Length,
+		&i.PointsPossible,
+		&i.SubmissionTypes,
+		&i.UnlockAt,
+		&i.LockAt,
+		&i.HtmlUrl,
+		&i.Published,
+		&i.AssignmentGroupID,
+		&i.Description,
+		&i.Version,
+		&i.Source,
+		&i.DescriptionText,
 	)
 	return i, err
 }
 
+const upsertAssignmentOverride = `-- name: UpsertAssignmentOverride :exec
+INSERT INTO assignment_overrides (assignment_id, name, due_date, difficulty, length, canvas_name, canvas_due_date, updated_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+ON CONFLICT(assignment_id) DO UPDATE SET
+    name = excluded.name,
+    due_date = excluded.due_date,
+    difficulty = excluded.difficulty,
+    length = excluded.length,
+    canvas_name = excluded.canvas_name,
+    canvas_due_date = excluded.canvas_due_date,
+    updated_at = excluded.updated_at
+`
+
+type UpsertAssignmentOverrideParams struct {
+	AssignmentID  int64          `json:"assignment_id"`
+	Name          sql.NullString `json:"name"`
+	DueDate       sql.NullTime   `json:"due_date"`
+	Difficulty    sql.NullInt64  `json:"difficulty"`
+	Length        sql.NullInt64  `json:"length"`
+	CanvasName    string         `json:"canvas_name"`
+	CanvasDueDate sql.NullTime   `json:"canvas_due_date"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+func (q *Queries) UpsertAssignmentOverride(ctx context.Context, arg UpsertAssignmentOverrideParams) error {
+	_, err := q.db.ExecContext(ctx, upsertAssignmentOverride,
+		arg.AssignmentID,
+		arg.Name,
+		arg.DueDate,
+		arg.Difficulty,
+		arg.Length,
+		arg.CanvasName,
+		arg.CanvasDueDate,
+		arg.UpdatedAt,
+	)
+	return err
+}
+
 const upsertCourse = `-- name: UpsertCourse :one
-INSERT INTO courses (id, name)
-VALUES (?1, ?2)
+INSERT INTO courses (id, name, term_id, term_name, start_at, end_at)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6)
 ON CONFLICT(id) DO UPDATE SET 
-    name = excluded.name
-RETURNING id, name, created_at
+    name = excluded.name,
+    term_id = excluded.term_id,
+    term_name = excluded.term_name,
+    start_at = excluded.start_at,
+    end_at = excluded.end_at
+RETURNING id, name, created_at, term_id, term_name, start_at, end_at
 `
 
 type UpsertCourseParams struct {
-	ID   int64  `json:"id"`
-	Name string `json:"name"`
+	ID       int64          `json:"id"`
+	Name     string         `json:"name"`
+	TermID   sql.NullInt64  `json:"term_id"`
+	TermName sql.NullString `json:"term_name"`
+	StartAt  sql.NullTime   `json:"start_at"`
+	EndAt    sql.NullTime   `json:"end_at"`
 }
 
 // queries.sql
 func (q *Queries) UpsertCourse(ctx context.Context, arg UpsertCourseParams) (Course, error) {
-	row := q.db.QueryRowContext(ctx, upsertCourse, arg.ID, arg.Name)
+	row := q.db.QueryRowContext(ctx, upsertCourse,
+		arg.ID,
+		arg.Name,
+		arg.TermID,
+		arg.TermName,
+		arg.StartAt,
+		arg.EndAt,
+	)
 	var i Course
-	err := row.Scan(&i.ID, &i.Name, &i.CreatedAt)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CreatedAt,
+		&i.TermID,
+		&i.TermName,
+		&i.StartAt,
+		&i.EndAt,
+	)
+	return i, err
+}
+
+const upsertCourseSyncState = `-- name: UpsertCourseSyncState :exec
+INSERT INTO course_sync_state (course_id, etag, last_synced_at)
+VALUES (?1, ?2, ?3)
+ON CONFLICT(course_id) DO UPDATE SET
+    etag = excluded.etag,
+    last_synced_at = excluded.last_synced_at
+`
+
+type UpsertCourseSyncStateParams struct {
+	CourseID     int64          `json:"course_id"`
+	Etag         sql.NullString `json:"etag"`
+	LastSyncedAt time.Time      `json:"last_synced_at"`
+}
+
+func (q *Queries) UpsertCourseSyncState(ctx context.Context, arg UpsertCourseSyncStateParams) error {
+	_, err := q.db.ExecContext(ctx, upsertCourseSyncState, arg.CourseID, arg.Etag, arg.LastSyncedAt)
+	return err
+}
+
+const upsertSubmission = `-- name: UpsertSubmission :exec
+INSERT INTO submissions (assignment_id, workflow_state, submitted_at, score, late, missing, excused)
+VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7)
+ON CONFLICT(assignment_id) DO UPDATE SET
+    workflow_state = excluded.workflow_state,
+    submitted_at = excluded.submitted_at,
+    score = excluded.score,
+    late = excluded.late,
+    missing = excluded.missing,
+    excused = excluded.excused
+`
+
+type UpsertSubmissionParams struct {
+	AssignmentID  int64           `json:"assignment_id"`
+	WorkflowState string          `json:"workflow_state"`
+	SubmittedAt   sql.NullTime    `json:"submitted_at"`
+	Score         sql.NullFloat64 `json:"score"`
+	Late          bool            `json:"late"`
+	Missing       bool            `json:"missing"`
+	Excused       bool            `json:"excused"`
+}
+
+func (q *Queries) UpsertSubmission(ctx context.Context, arg UpsertSubmissionParams) error {
+	_, err := q.db.ExecContext(ctx, upsertSubmission,
+		arg.AssignmentID,
+		arg.WorkflowState,
+		arg.SubmittedAt,
+		arg.Score,
+		arg.Late,
+		arg.Missing,
+		arg.Excused,
+	)
+	return err
+}
+
+const upsertSyllabus = `-- name: UpsertSyllabus :one
+INSERT INTO syllabi (course_id, filename, content, uploaded_at)
+VALUES (?1, ?2, ?3, ?4)
+ON CONFLICT(course_id, filename) DO UPDATE SET
+    content = excluded.content,
+    uploaded_at = excluded.uploaded_at
+RETURNING id, course_id, filename, content, uploaded_at
+`
+
+type UpsertSyllabusParams struct {
+	CourseID   int64     `json:"course_id"`
+	Filename   string    `json:"filename"`
+	Content    string    `json:"content"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+func (q *Queries) UpsertSyllabus(ctx context.Context, arg UpsertSyllabusParams) (Syllabus, error) {
+	row := q.db.QueryRowContext(ctx, upsertSyllabus,
+		arg.CourseID,
+		arg.Filename,
+		arg.Content,
+		arg.UploadedAt,
+	)
+	var i Syllabus
+	err := row.Scan(
+		&i.ID,
+		&i.CourseID,
+		&i.Filename,
+		&i.Content,
+		&i.UploadedAt,
+	)
 	return i, err
 }
@@ -6,20 +6,96 @@ package sqlite
 
 import (
 	"database/sql"
+	"time"
 )
 
 type Assignment struct {
-	ID         int64         `json:"id"`
-	CourseID   int64         `json:"course_id"`
-	Name       string        `json:"name"`
-	DueDate    sql.NullTime  `json:"due_date"`
-	CreatedAt  sql.NullTime  `json:"created_at"`
-	Difficulty sql.NullInt64 `json:"difficulty"`
-	Length     sql.NullInt64 `json:"length"`
+	ID                int64           `json:"id"`
+	CourseID          int64           `json:"course_id"`
+	Name              string          `json:"name"`
+	DueDate           sql.NullTime    `json:"due_date"`
+	CreatedAt         sql.NullTime    `json:"created_at"`
+	Difficulty        sql.NullInt64   `json:"difficulty"`
+	Length            sql.NullInt64   `json:"length"`
+	PointsPossible    sql.NullFloat64 `json:"points_possible"`
+	SubmissionTypes   sql.NullString  `json:"submission_types"`
+	UnlockAt          sql.NullTime    `json:"unlock_at"`
+	LockAt            sql.NullTime    `json:"lock_at"`
+	HtmlUrl           sql.NullString  `json:"html_url"`
+	Published         bool            `json:"published"`
+	AssignmentGroupID sql.NullInt64   `json:"assignment_group_id"`
+	Description       sql.NullString  `json:"description"`
+	Version           int64           `json:"version"`
+	Source            string          `json:"source"`
+	DescriptionText   sql.NullString  `json:"description_text"`
+}
+
+type AssignmentEvent struct {
+	ID           int64          `json:"id"`
+	AssignmentID int64          `json:"assignment_id"`
+	CourseID     int64          `json:"course_id"`
+	EventType    string         `json:"event_type"`
+	OldValue     sql.NullString `json:"old_value"`
+	NewValue     sql.NullString `json:"new_value"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type AssignmentOverride struct {
+	AssignmentID  int64          `json:"assignment_id"`
+	Name          sql.NullString `json:"name"`
+	DueDate       sql.NullTime   `json:"due_date"`
+	Difficulty    sql.NullInt64  `json:"difficulty"`
+	Length        sql.NullInt64  `json:"length"`
+	CanvasName    string         `json:"canvas_name"`
+	CanvasDueDate sql.NullTime   `json:"canvas_due_date"`
+	UpdatedAt     time.Time      `json:"updated_at"`
 }
 
 type Course struct {
-	ID        int64        `json:"id"`
-	Name      string       `json:"name"`
-	CreatedAt sql.NullTime `json:"created_at"`
+	ID        int64          `json:"id"`
+	Name      string         `json:"name"`
+	CreatedAt sql.NullTime   `json:"created_at"`
+	TermID    sql.NullInt64  `json:"term_id"`
+	TermName  sql.NullString `json:"term_name"`
+	StartAt   sql.NullTime   `json:"start_at"`
+	EndAt     sql.NullTime   `json:"end_at"`
+}
+
+type CourseSyncState struct {
+	CourseID     int64          `json:"course_id"`
+	Etag         sql.NullString `json:"etag"`
+	LastSyncedAt time.Time      `json:"last_synced_at"`
+}
+
+type Submission struct {
+	AssignmentID  int64           `json:"assignment_id"`
+	WorkflowState string          `json:"workflow_state"`
+	SubmittedAt   sql.NullTime    `json:"submitted_at"`
+	Score         sql.NullFloat64 `json:"score"`
+	Late          bool            `json:"late"`
+	Missing       bool            `json:"missing"`
+	Excused       bool            `json:"excused"`
+}
+
+type Syllabus struct {
+	ID         int64     `json:"id"`
+	CourseID   int64     `json:"course_id"`
+	Filename   string    `json:"filename"`
+	Content    string    `json:"content"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+type SyncRun struct {
+	ID                int64          `json:"id"`
+	Source            string         `json:"source"`
+	StartedAt         time.Time      `json:"started_at"`
+	FinishedAt        sql.NullTime   `json:"finished_at"`
+	CoursesSynced     int64          `json:"courses_synced"`
+	AssignmentsSynced int64          `json:"assignments_synced"`
+	Error             sql.NullString `json:"error"`
+}
+
+type TaskSequence struct {
+	ID     int64 `json:"id"`
+	LastID int64 `json:"last_id"`
 }
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+)
+
+// Reports which cache the service is using (redis, memory or none). Responds with
+// 503 if the cache stopped answering.
+func GetHealth(c *gin.Context) {
+	cache := redis.GetInstance()
+	if err := cache.Ping(c.Request.Context()); err != nil {
+		fmt.Printf("Error pinging cache: %v\n", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "unavailable",
+			"cache":  cache.Name(),
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"cache":  cache.Name(),
+	})
+}
@@ -3,149 +3,112 @@ package main
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
-	"path/filepath"
-
-	_ "github.com/mattn/go-sqlite3"
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
-
-	_ "embed"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/johncmanuel/cpsc449-project2/db/migrations"
 	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
 	"github.com/johncmanuel/cpsc449-project2/pkgs/canvas"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/config"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/llm"
 	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
-	"github.com/johncmanuel/cpsc449-project2/pkgs/utils"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/syncer"
 )
 
-var uploadedFilesDir = "./uploads"
-
-//go:embed db/sqlite/schema.sql
-var ddl string
-
-// for OpenAI request
-type Assignment struct {
-	ID         string `json:"id"`
-	Name       string `json:"name"`
-	CourseID   string `json:"course_id"`
-	DueDate    string `json:"due_date"`
-	Difficulty string `json:"difficulty,omitempty"`
-	Length     string `json:"length,omitempty"`
+// How the read endpoints cache what they get from the DB: values are still served for
+// a bit after their TTL while they're refreshed, and 404s are remembered briefly
+var readCacheOptions = redis.LoadOptions{
+	StaleWhileRevalidate: 30 * time.Second,
+	NegativeTTL:          30 * time.Second,
 }
 
 // Just for printing and testing the API
-func ExampleCanvasAssignmentFetcher(c *canvas.CanvasClient) {
-	allAssignments, err := c.GetAllAssignmentsForCurrentTerm()
+func ExampleCanvasAssignmentFetcher(ctx context.Context, c *canvas.CanvasClient) {
+	allAssignments, err := c.GetAllAssignmentsForCurrentTermContext(ctx)
 	if err != nil {
 		fmt.Printf("Error fetching assignments: %v\n", err)
 		return
 	}
 
-	for courseID, courseAssignments := range allAssignments {
-		for courseName, assignments := range courseAssignments {
-			fmt.Printf("Course: %s (ID: %d)\n", courseName, courseID)
-			for _, assignment := range assignments {
-				fmt.Printf("- Assignment: %s (ID: %d), Due: %s\n",
-					assignment.Name, assignment.ID, assignment.DueAt)
-			}
+	for _, courseAssignments := range allAssignments {
+		course := courseAssignments.Course
+		fmt.Printf("Course: %s (ID: %d)\n", course.Name, course.ID)
+		for _, assignment := range courseAssignments.Assignments {
+			fmt.Printf("- Assignment: %s (ID: %d), Due: %s\n",
+				assignment.Name, assignment.ID, assignment.DueAt)
 		}
 	}
 }
 
-// Fetch assignments from Canvas and insert into sqlite db
-func HandleAssignments(c *canvas.CanvasClient, q *sqlite.Queries) {
-	allAssignments, err := c.GetAllAssignmentsForCurrentTerm()
+// Gets an individual assignment, from the cache when it's there
+func GetAssignment(c *gin.Context, q store.Store) {
+	courseID, err := strconv.ParseInt(c.Param("courseID"), 10, 64)
 	if err != nil {
-		fmt.Printf("Error fetching assignments: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid course ID",
+		})
 		return
 	}
-	for courseID, courseAssignments := range allAssignments {
-		for courseName, assignments := range courseAssignments {
-			fmt.Printf("Course: %s (ID: %d)\n", courseName, courseID)
-			for _, assignment := range assignments {
-				fmt.Printf("- Assignment: %s (ID: %d), Due: %s\n",
-					assignment.Name, assignment.ID, assignment.DueAt)
-				// TODO: add course name to the DB
-				params := sqlite.UpsertAssignmentParams{
-					ID:         int64(assignment.ID),
-					CourseID:   int64(courseID),
-					Name:       assignment.Name,
-					DueDate:    utils.ConvertToNullTime(assignment.DueAt),
-					Difficulty: sql.NullInt64{},
-					Length:     sql.NullInt64{},
-				}
-				if _, err := q.UpsertAssignment(context.Background(), params); err != nil {
-					fmt.Printf("Error inserting assignment: %v\n", err)
-				}
-			}
-		}
-	}
-}
-
-// Gets an individual assignment from the DB
-func GetAssignment(c *gin.Context, q *sqlite.Queries) {
-	courseID := c.Param("courseID")
-	assignmentID := c.Param("assignmentID")
-	r := redis.GetInstance()
-	keys := redis.GenerateTupleKey(courseID, assignmentID)
-
-	// Check if it exists in cache first
-	// if it does, return it
-	// if not, get it from the DB and cache it
-	// if it doesn't exist in the DB, return 404
-	e, err := r.Exists(keys)
+	assignmentID, err := strconv.ParseInt(c.Param("assignmentID"), 10, 64)
 	if err != nil {
-		fmt.Printf("Error checking for key: %v\n", err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid assignment ID",
+		})
+		return
 	}
-	if !e {
-		fmt.Println("Value not found in cache, retrieving from DB.")
-		// get from the DB
-		params := sqlite.GetAssignmentParams{
-			CourseID: utils.ConvertStringToInt64(courseID),
-			ID:       utils.ConvertStringToInt64(assignmentID),
-		}
-		assignment, err := q.GetAssignment(context.Background(), params)
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"error": "Assignment not found",
-			})
-			return
-		}
-		// cache it
-		if err := r.Set(keys, assignment); err != nil {
-			fmt.Printf("Error caching assignment: %v\n", err)
-		}
-		c.JSON(http.StatusOK, assignment)
+	params := sqlite.GetAssignmentParams{
+		CourseID: courseID,
+		ID:       assignmentID,
+	}
+	key := redis.Key(redis.KindAssignment, params.CourseID, params.ID)
+
+	assignment, err := redis.GetOrLoad(c.Request.Context(), redis.GetInstance(), redis.KindAssignment, key, readCacheOptions,
+		func(ctx context.Context) (sqlite.Assignment, error) {
+			return q.GetAssignment(ctx, params)
+		})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Assignment not found",
+		})
 		return
 	}
-	// get from cache
-	val, err := r.Get(keys)
 	if err != nil {
-		fmt.Printf("Error getting key: %v\n", err)
+		fmt.Printf("Error fetching assignment: %v\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Internal server error",
 		})
 		return
 	}
-	fmt.Println("retrieving from cache lfg")
-	// deserialize json back to struct
-	var cachedAssignment sqlite.Assignment
-	if err := json.Unmarshal([]byte(val), &cachedAssignment); err != nil {
-		fmt.Printf("Error unmarshalling assignment: %v\n", err)
-	}
-	c.JSON(http.StatusOK, cachedAssignment)
+	c.Header("ETag", assignmentETag(assignment))
+	c.JSON(http.StatusOK, assignment)
 }
 
-// Simple function to get all assignments directly from DB
-func GetAllAssignments(c *gin.Context, q *sqlite.Queries) {
-	// Attempt to fetch all assignments from the DB
-	assignments, err := q.ListAllAssignments(context.Background())
+// Gets a page of assignments, filtered and sorted by the query string. The ones that
+// were already turned in are left out unless ?include_submitted=true.
+func GetAllAssignments(c *gin.Context, q store.Store) {
+	query, err := parseAssignmentQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Every replica asks for the same pages, only one of them loads each
+	opts := readCacheOptions
+	opts.Lock = true
+	page, err := redis.GetOrLoad(c.Request.Context(), redis.GetInstance(), redis.KindAssignmentList,
+		redis.Key(redis.KindAssignmentList, query.cacheKey()), opts,
+		func(ctx context.Context) (assignmentPage, error) {
+			return listAssignments(ctx, q, query)
+		})
 	if err != nil {
 		// If an error occurs, print it and return a server error response
 		fmt.Printf("Error fetching assignments from DB: %v\n", err)
@@ -155,30 +118,42 @@ func GetAllAssignments(c *gin.Context, q *sqlite.Queries) {
 		return
 	}
 
-	// Return the assignments as JSON if successful
-	c.JSON(http.StatusOK, assignments)
+	// Return the page as JSON if successful
+	c.JSON(http.StatusOK, page)
 }
 
-func DeleteAssignment(c *gin.Context, q *sqlite.Queries) {
-	courseID := c.Param("courseID")
-	assignmentID := c.Param("assignmentID")
+func DeleteAssignment(c *gin.Context, q store.Store) {
+	courseID, err := strconv.ParseInt(c.Param("courseID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid course ID",
+		})
+		return
+	}
+	assignmentID, err := strconv.ParseInt(c.Param("assignmentID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid assignment ID",
+		})
+		return
+	}
+	ctx := c.Request.Context()
 	r := redis.GetInstance()
-	keys := redis.GenerateTupleKey(courseID, assignmentID)
 	params := sqlite.DeleteAssignmentParams{
-		CourseID: utils.ConvertStringToInt64(courseID),
-		ID:       utils.ConvertStringToInt64(assignmentID),
+		CourseID: courseID,
+		ID:       assignmentID,
 	}
 
-	if err := q.DeleteAssignment(context.Background(), params); err != nil {
+	if err := q.DeleteAssignment(ctx, params); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Assignment not found",
 		})
 		return
 	}
 
-	// remove from cache if its there
-	if err := r.Delete(keys); err != nil {
-		fmt.Printf("Error deleting key from cache: %v\n", err)
+	// remove it from the cache along with the lists it's in, on every replica
+	if err := r.InvalidateContext(ctx, redis.AssignmentInvalidation(params.CourseID, params.ID)); err != nil {
+		fmt.Printf("Error invalidating cached assignment: %v\n", err)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -186,108 +161,218 @@ func DeleteAssignment(c *gin.Context, q *sqlite.Queries) {
 	})
 }
 
-func SetupRouter(cli *canvas.CanvasClient, q *sqlite.Queries, ocli *openai.Client) *gin.Engine {
+func SetupRouter(cli *canvas.CanvasClient, q store.Store, provider llm.Provider, s *syncer.Syncer) *gin.Engine {
 	r := gin.Default()
 
+	// Reports which cache is in use and whether it's reachable
+	r.GET("/health", GetHealth)
+
 	// Test route
 	r.GET("/test", func(c *gin.Context) {
-		ExampleCanvasAssignmentFetcher(cli)
+		ExampleCanvasAssignmentFetcher(c.Request.Context(), cli)
 	})
 
 	r.GET("/:courseID/assignments/:assignmentID", func(c *gin.Context) {
 		GetAssignment(c, q)
 	})
-	// r.PUT("/:courseID/assignments/:assignmentID", UpdateAssignment)
+	// Overrides the assignment's name, due date, difficulty or length with the user's
+	// own, which survive syncs. PUT replaces them all, PATCH takes a JSON merge patch,
+	// and both honor If-Match with the ETag from GET.
+	r.PUT("/:courseID/assignments/:assignmentID", func(c *gin.Context) {
+		UpdateAssignment(c, q)
+	})
+	r.PATCH("/:courseID/assignments/:assignmentID", func(c *gin.Context) {
+		PatchAssignment(c, q)
+	})
 	r.DELETE("/:courseID/assignments/:assignmentID", func(c *gin.Context) {
 		DeleteAssignment(c, q)
 	})
-	r.GET("/assignments", func(c *gin.Context) {
-		// ExampleCanvasAssignmentFetcher(cli)
-		HandleAssignments(cli, q)
+	// Tasks created by hand, alongside the assignments from Canvas
+	r.POST("/tasks", func(c *gin.Context) {
+		PostTask(c, q)
+	})
+	r.GET("/tasks", func(c *gin.Context) {
+		GetTasks(c, q)
+	})
+	r.GET("/tasks/:taskID", func(c *gin.Context) {
+		GetTask(c, q)
+	})
+	r.PUT("/tasks/:taskID", func(c *gin.Context) {
+		UpdateTask(c, q)
+	})
+	r.PATCH("/tasks/:taskID", func(c *gin.Context) {
+		PatchTask(c, q)
+	})
+	r.DELETE("/tasks/:taskID", func(c *gin.Context) {
+		DeleteTask(c, q)
+	})
+	// Syncs from Canvas, ?term= backfills a past term and ?course_id= only syncs one
+	// course. With ?async=true it returns a job to poll at /sync/:jobID instead.
+	r.POST("/sync", func(c *gin.Context) {
+		PostSync(c, s)
+	})
+	r.GET("/sync/runs", func(c *gin.Context) {
+		GetSyncRuns(c, q)
 	})
+	r.GET("/sync/:jobID", GetSyncJob)
 
-	// Route to get all assignments directly from the DB (no caching)
+	// What changed on Canvas between syncs, ?since= defaults to a week ago
+	r.GET("/changes", func(c *gin.Context) {
+		GetChanges(c, q)
+	})
+
+	r.GET("/courses", func(c *gin.Context) {
+		GetAllCourses(c, q)
+	})
+	r.GET("/courses/:courseID", func(c *gin.Context) {
+		GetCourse(c, q)
+	})
+	r.GET("/courses/:courseID/assignments", func(c *gin.Context) {
+		GetCourseAssignments(c, q)
+	})
+
+	// Route to get all assignments, ?include_submitted=true includes the ones already
+	// turned in
 	r.GET("/all-assignments", func(c *gin.Context) {
 		GetAllAssignments(c, q)
 	})
 
+	// Ranks the upcoming assignments using the AI model, GET returns the latest ranking
+	r.POST("/prioritize", func(c *gin.Context) {
+		PostPrioritize(c, provider, q)
+	})
+	r.GET("/prioritize", GetPrioritize)
+
 	// It's possible to get the syllabus through Canvas API; however, some
 	// teachers only upload their syllabus file in the the syllabus page. The API returns
 	// the HTML content of the syllabus page, so not sure if the API would be able to return the file
 	r.POST("/syllabus", func(c *gin.Context) {
-		f, err := c.FormFile("file")
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "No file uploaded",
-			})
-			return
-		}
-
-		fpath := filepath.Join(uploadedFilesDir, f.Filename)
-
-		if err := os.MkdirAll(uploadedFilesDir, os.ModePerm); err != nil {
-			log.Println("Error creating directory:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Internal server error",
-			})
-			return
-		}
-
-		if err := c.SaveUploadedFile(f, fpath); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to save file",
-			})
-			return
-		}
+		PostSyllabus(c, q)
+	})
 
-		c.JSON(http.StatusOK, gin.H{
-			"filename": f.Filename,
-			"message":  "File uploaded successfully",
-		})
+	// Full-text search over the assignments and syllabi
+	r.GET("/search", func(c *gin.Context) {
+		GetSearch(c, q)
 	})
 	return r
 }
 
-func main() {
-	// Load environment variables
-	utils.LoadEnv()
-
-	var (
-		BASE_CANVAS_URL = utils.GetEnv("CANVAS_URL")
-		CANVAS_TOKEN    = utils.GetEnv("CANVAS_TOKEN")
-	)
-
-	// Open SQLite database connection
-	db, err := sql.Open("sqlite3", "./db/canvas.db")
+// Opens the SQLite or Postgres database DATABASE_URL points to, along with the
+// migrations for it
+func openDatabase(databaseURL string) (*store.Backend, *migrations.Migrator) {
+	db, err := store.Open(context.Background(), databaseURL)
 	if err != nil {
 		panic(fmt.Sprintf("Error opening database: %v", err))
 	}
-	defer db.Close()
+	migrator, err := migrations.New(db.DB, db.Dialect)
+	if err != nil {
+		panic(fmt.Sprintf("Error loading migrations: %v", err))
+	}
+	return db, migrator
+}
 
-	// Create the tables if they don't exist
-	if _, err := db.ExecContext(context.Background(), ddl); err != nil {
-		panic(fmt.Sprintf("Error creating tables: %v", err))
+func main() {
+	// `go run . migrate ...` only touches the database, so it doesn't need the rest of
+	// the configuration
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		db, migrator := openDatabase(config.LoadDatabaseURL())
+		err := runMigrate(context.Background(), migrator, os.Args[2:])
+		db.Close()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	q := sqlite.New(db)
+	// Load environment variables
+	cfg := config.Load()
 
-	// Initialize Canvas client
-	client := canvas.NewCanvasClient(BASE_CANVAS_URL, CANVAS_TOKEN)
+	db, migrator := openDatabase(cfg.DatabaseURL)
+	defer db.Close()
 
-	// Retrieve the OpenAI API key from environment variables
-	apiKey := utils.GetEnv("OPENAI_API_KEY")
-	if apiKey == "" {
-		fmt.Println("OPENAI_API_KEY is not set in the environment variables")
-		return
+	if cfg.MigrateOnStart {
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			panic(fmt.Sprintf("Error migrating database: %v", err))
+		}
+		for _, migration := range applied {
+			fmt.Printf("Applied migration %s\n", migration)
+		}
+	} else {
+		statuses, err := migrator.Status(context.Background())
+		if err != nil {
+			panic(fmt.Sprintf("Error checking migrations: %v", err))
+		}
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				fmt.Printf("Migration %s is pending, run `go run . migrate up`\n", status.Migration)
+			}
+		}
+	}
+	fmt.Printf("Using %s database\n", db.Dialect)
+	if err := fillDescriptionText(context.Background(), db); err != nil {
+		fmt.Printf("Error indexing descriptions: %v\n", err)
 	}
 
-	// Initialize the OpenAI client
-	openAIclient := openai.NewClient(
-		option.WithAPIKey("My API Key"),
-	)
+	// Initialize Canvas client
+	client := canvas.NewCanvasClient(cfg.CanvasURL, cfg.CanvasToken)
+	client.TermID = cfg.CanvasTermID
+	client.MaxRetries = cfg.CanvasMaxRetries
+	client.Concurrency = cfg.CanvasConcurrency
+	client.Timeout = cfg.CanvasTimeout
+
+	// Falls back to an in-process cache (or none) while Redis is down
+	redis.REDIS_OPTIONS = redis.Options{
+		URL:              cfg.RedisURL,
+		SentinelMaster:   cfg.RedisSentinelMaster,
+		SentinelAddrs:    cfg.RedisSentinelAddrs,
+		SentinelPassword: cfg.RedisSentinelPassword,
+		ClusterAddrs:     cfg.RedisClusterAddrs,
+		PoolSize:         cfg.RedisPoolSize,
+		DialTimeout:      cfg.RedisDialTimeout,
+		ReadTimeout:      cfg.RedisReadTimeout,
+		WriteTimeout:     cfg.RedisWriteTimeout,
+		KeyPrefix:        cfg.RedisKeyPrefix,
+	}
+	redis.REDIS_TIMEOUT = cfg.RedisTimeout
+	redis.CACHE_FALLBACK = cfg.CacheFallback
+	redis.CACHE_SIZE = cfg.CacheSize
+	redis.CACHE_L1_SIZE = cfg.CacheL1Size
+	redis.CACHE_L1_TTL = cfg.CacheL1TTL
+	redis.CACHE_HEALTH_INTERVAL = cfg.CacheHealthInterval
+	if err := redis.SetTTLs(cfg.CacheTTLs); err != nil {
+		panic(fmt.Sprintf("Error reading CACHE_TTLS: %v", err))
+	}
+	fmt.Printf("Using %s cache\n", redis.GetInstance().Name())
+
+	// Initialize the AI provider, works without an API key by falling back to the
+	// offline heuristic provider
+	provider, err := llm.NewProvider(llm.Options{
+		Provider:       cfg.LLMProvider,
+		APIKey:         cfg.OpenAIAPIKey,
+		BaseURL:        cfg.OpenAIBaseURL,
+		Model:          cfg.OpenAIModel,
+		EmbeddingModel: cfg.OpenAIEmbeddingModel,
+		CassettePath:   cfg.LLMCassette,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Error initializing AI provider: %v", err))
+	}
+	fmt.Printf("Using AI provider: %s\n", provider.Name())
+
+	// Keep the DB in sync with Canvas in the background
+	s := syncer.New(client, db, syncer.Options{
+		Interval:          cfg.SyncInterval,
+		Jitter:            cfg.SyncJitter,
+		RunOnStart:        cfg.SyncOnStartup,
+		LockTTL:           cfg.SyncLockTTL,
+		RequireSharedLock: cfg.SyncRequireSharedLock,
+	})
+	s.Start(context.Background())
 
 	// Set up the router with dependencies
-	router := SetupRouter(client, q, openAIclient)
+	router := SetupRouter(client, db, provider, s)
 
 	// test redis
 	// r := redis.GetInstance()
@@ -302,14 +387,8 @@ func main() {
 	// 	fmt.Println("REDIS: Key not found, which is expected", err)
 	// }
 
-	// Determine server port
-	port := utils.GetEnv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	fmt.Printf("Server starting on port %s...\n", port)
-	if err := router.Run(":" + port); err != nil {
+	fmt.Printf("Server starting on port %s...\n", cfg.Port)
+	if err := router.Run(":" + cfg.Port); err != nil {
 		panic(fmt.Sprintf("Failed to start server: %v", err))
 	}
 }
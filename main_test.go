@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/migrations"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// Opens an in-memory database with every migration applied and swaps the shared cache
+// for an in-process one, so tests need neither a canvas.db nor Redis
+func newTestStore(t *testing.T) store.Store {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: gets its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrations.New(db, migrations.DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	redis.SetInstance(redis.NewMemoryCache(100))
+	return store.NewSQLite(db)
+}
+
+func TestAssignmentInvalidIDs(t *testing.T) {
+	q := newTestStore(t)
+	r := gin.New()
+	r.GET("/:courseID/assignments/:assignmentID", func(c *gin.Context) {
+		GetAssignment(c, q)
+	})
+	r.DELETE("/:courseID/assignments/:assignmentID", func(c *gin.Context) {
+		DeleteAssignment(c, q)
+	})
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/abc/assignments/1", http.StatusBadRequest},
+		{http.MethodGet, "/449/assignments/abc", http.StatusBadRequest},
+		{http.MethodGet, "/449/assignments/1", http.StatusNotFound},
+		{http.MethodDelete, "/abc/assignments/1", http.StatusBadRequest},
+		{http.MethodDelete, "/449/assignments/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s = %d %s, want %d", tt.method, tt.path, w.Code, w.Body, tt.want)
+		}
+	}
+}
+
+func TestGetAllCoursesEmpty(t *testing.T) {
+	q := newTestStore(t)
+	// The course of personal assignments is added by the migrations
+	if err := q.DeleteCourse(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/courses", func(c *gin.Context) {
+		GetAllCourses(c, q)
+	})
+
+	// The second request is answered from the cache
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/courses", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "[]" {
+			t.Errorf("GET /courses without courses = %d %s, want 200 []", w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/johncmanuel/cpsc449-project2/db/migrations"
+)
+
+const migrateUsage = "usage: go run . migrate up|down [n]|status"
+
+// Handles the migrate subcommand: up applies every pending migration, down rolls back
+// the last n (1 by default) and status lists them all
+func runMigrate(ctx context.Context, m *migrations.Migrator, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("Applied %s\n", migration)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("Already up to date")
+		}
+		return err
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid number of migrations: %s", args[1])
+			}
+			n = parsed
+		}
+		rolledBack, err := m.Down(ctx, n)
+		for _, migration := range rolledBack {
+			fmt.Printf("Rolled back %s\n", migration)
+		}
+		if err == nil && len(rolledBack) == 0 {
+			fmt.Println("Nothing to roll back")
+		}
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		legacy, err := m.Legacy(ctx)
+		if err != nil {
+			return err
+		}
+		if legacy {
+			fmt.Println("Database predates migrations, `go run . migrate up` upgrades it")
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-30s %s\n", status.Migration, state)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
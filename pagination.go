@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// Orders /all-assignments can be sorted in. Assignments without a due date (or a
+// difficulty) come last, ties are broken by ID so pages never overlap.
+const (
+	sortDueDate    = "due_date"
+	sortDifficulty = "-difficulty"
+	sortName       = "name"
+)
+
+// Page sizes of /all-assignments
+const (
+	defaultAssignmentsLimit = 50
+	maxAssignmentsLimit     = 200
+)
+
+// Filters, sort and page asked for in the /all-assignments query string
+type assignmentQuery struct {
+	IncludeSubmitted bool
+	CourseID         sql.NullInt64
+	DueAfter         sql.NullTime
+	DueBefore        sql.NullTime
+	Overdue          sql.NullBool
+	MinDifficulty    sql.NullInt64
+	HasDueDate       sql.NullBool
+	Sort             string
+	Limit            int64
+	Cursor           *assignmentCursor
+
+	// When overdue is worked out from, kept to the minute so the page can be cached
+	Now time.Time
+}
+
+// Position after the last assignment of a page. Only the field of the sort it was made
+// for is set, and it's left nil when the assignment didn't have a value for it.
+type assignmentCursor struct {
+	Sort       string     `json:"sort"`
+	ID         int64      `json:"id"`
+	DueDate    *time.Time `json:"due_date,omitempty"`
+	Difficulty *int64     `json:"difficulty,omitempty"`
+	Name       string     `json:"name,omitempty"`
+}
+
+// One page of /all-assignments. Total counts every assignment matching the filters,
+// not just the ones on this page.
+type assignmentPage struct {
+	Assignments []sqlite.Assignment `json:"assignments"`
+	NextCursor  *string             `json:"next_cursor"`
+	Total       int64               `json:"total"`
+}
+
+// Cursors are opaque to clients, they're just base64 encoded JSON
+func encodeCursor(cursor assignmentCursor) (string, error) {
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func decodeCursor(s string) (*assignmentCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor assignmentCursor
+	if err := json.Unmarshal(b, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func cursorAfter(sort string, a sqlite.Assignment) assignmentCursor {
+	cursor := assignmentCursor{Sort: sort, ID: a.ID}
+	switch sort {
+	case sortDueDate:
+		if a.DueDate.Valid {
+			due := a.DueDate.Time.UTC()
+			cursor.DueDate = &due
+		}
+	case sortDifficulty:
+		if a.Difficulty.Valid {
+			cursor.Difficulty = &a.Difficulty.Int64
+		}
+	case sortName:
+		cursor.Name = a.Name
+	}
+	return cursor
+}
+
+func parseQueryTime(c *gin.Context, param string) (sql.NullTime, error) {
+	s := c.Query(param)
+	if s == "" {
+		return sql.NullTime{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return sql.NullTime{}, invalidFieldError{param, "must be an RFC3339 time"}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+}
+
+func parseQueryInt(c *gin.Context, param string) (sql.NullInt64, error) {
+	s := c.Query(param)
+	if s == "" {
+		return sql.NullInt64{}, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return sql.NullInt64{}, invalidFieldError{param, "must be an integer"}
+	}
+	return sql.NullInt64{Int64: n, Valid: true}, nil
+}
+
+func parseQueryBool(c *gin.Context, param string) (sql.NullBool, error) {
+	s := c.Query(param)
+	if s == "" {
+		return sql.NullBool{}, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return sql.NullBool{}, invalidFieldError{param, "must be true or false"}
+	}
+	return sql.NullBool{Bool: b, Valid: true}, nil
+}
+
+// Reads the /all-assignments query string, the errors are meant for the client
+func parseAssignmentQuery(c *gin.Context) (assignmentQuery, error) {
+	query := assignmentQuery{
+		IncludeSubmitted: c.Query("include_submitted") == "true",
+		Sort:             sortDueDate,
+		Limit:            defaultAssignmentsLimit,
+	}
+	var err error
+	if query.CourseID, err = parseQueryInt(c, "course_id"); err != nil {
+		return query, err
+	}
+	if query.DueAfter, err = parseQueryTime(c, "due_after"); err != nil {
+		return query, err
+	}
+	if query.DueBefore, err = parseQueryTime(c, "due_before"); err != nil {
+		return query, err
+	}
+	if query.Overdue, err = parseQueryBool(c, "overdue"); err != nil {
+		return query, err
+	}
+	if query.MinDifficulty, err = parseQueryInt(c, "min_difficulty"); err != nil {
+		return query, err
+	}
+	if query.HasDueDate, err = parseQueryBool(c, "has_due_date"); err != nil {
+		return query, err
+	}
+	if query.Overdue.Valid {
+		query.Now = time.Now().UTC().Truncate(time.Minute)
+	}
+
+	if s := c.Query("sort"); s != "" {
+		if s != sortDueDate && s != sortDifficulty && s != sortName {
+			return query, invalidFieldError{"sort", fmt.Sprintf("must be one of %s, %s or %s", sortDueDate, sortDifficulty, sortName)}
+		}
+		query.Sort = s
+	}
+
+	limit, err := parseQueryInt(c, "limit")
+	if err != nil {
+		return query, err
+	}
+	if limit.Valid {
+		if limit.Int64 <= 0 || limit.Int64 > maxAssignmentsLimit {
+			return query, invalidFieldError{"limit", fmt.Sprintf("must be between 1 and %d", maxAssignmentsLimit)}
+		}
+		query.Limit = limit.Int64
+	}
+
+	if s := c.Query("cursor"); s != "" {
+		cursor, err := decodeCursor(s)
+		if err != nil {
+			return query, invalidFieldError{"cursor", "is invalid"}
+		}
+		if cursor.Sort != query.Sort {
+			return query, invalidFieldError{"cursor", "was made for another sort"}
+		}
+		query.Cursor = cursor
+	}
+	return query, nil
+}
+
+// Identifies the page in the cache. The query string itself can't be used since the
+// same page can be asked for in many ways.
+func (query assignmentQuery) cacheKey() string {
+	b, _ := json.Marshal(query)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
+
+// Fetches a page of assignments and counts every one matching the filters. The due
+// date sort walks idx_assignments_due_date, or idx_assignments_course_id when it's
+// filtered on a course; the other sorts have to sort what the filters leave.
+func listAssignments(ctx context.Context, q store.Store, query assignmentQuery) (assignmentPage, error) {
+	now := sql.NullTime{Time: query.Now, Valid: query.Overdue.Valid}
+	var (
+		afterID         sql.NullInt64
+		afterDueDate    sql.NullTime
+		afterDifficulty sql.NullInt64
+		afterName       string
+	)
+	if query.Cursor != nil {
+		afterID = sql.NullInt64{Int64: query.Cursor.ID, Valid: true}
+		if query.Cursor.DueDate != nil {
+			afterDueDate = sql.NullTime{Time: query.Cursor.DueDate.UTC(), Valid: true}
+		}
+		if query.Cursor.Difficulty != nil {
+			afterDifficulty = sql.NullInt64{Int64: *query.Cursor.Difficulty, Valid: true}
+		}
+		afterName = query.Cursor.Name
+	}
+
+	// One more than asked for, to know if there's another page
+	limit := query.Limit + 1
+
+	var (
+		assignments []sqlite.Assignment
+		total       int64
+		err         error
+	)
+	switch {
+	case query.Sort == sortDueDate && query.CourseID.Valid:
+		assignments, err = q.ListCourseAssignmentsByDueDate(ctx, sqlite.ListCourseAssignmentsByDueDateParams{
+			IncludeSubmitted: query.IncludeSubmitted,
+			CourseID:         query.CourseID.Int64,
+			DueAfter:         query.DueAfter,
+			DueBefore:        query.DueBefore,
+			Overdue:          query.Overdue,
+			Now:              now,
+			MinDifficulty:    query.MinDifficulty,
+			HasDueDate:       query.HasDueDate,
+			AfterID:          afterID,
+			AfterDueDate:     afterDueDate,
+			Limit:            limit,
+		})
+	case query.Sort == sortDueDate:
+		assignments, err = q.ListAssignmentsByDueDate(ctx, sqlite.ListAssignmentsByDueDateParams{
+			IncludeSubmitted: query.IncludeSubmitted,
+			CourseID:         query.CourseID,
+			DueAfter:         query.DueAfter,
+			DueBefore:        query.DueBefore,
+			Overdue:          query.Overdue,
+			Now:              now,
+			MinDifficulty:    query.MinDifficulty,
+			HasDueDate:       query.HasDueDate,
+			AfterID:          afterID,
+			AfterDueDate:     afterDueDate,
+			Limit:            limit,
+		})
+	case query.Sort == sortDifficulty:
+		assignments, err = q.ListAssignmentsByDifficulty(ctx, sqlite.ListAssignmentsByDifficultyParams{
+			IncludeSubmitted: query.IncludeSubmitted,
+			CourseID:         query.CourseID,
+			DueAfter:         query.DueAfter,
+			DueBefore:        query.DueBefore,
+			Overdue:          query.Overdue,
+			Now:              now,
+			MinDifficulty:    query.MinDifficulty,
+			HasDueDate:       query.HasDueDate,
+			AfterID:          afterID,
+			AfterDifficulty:  afterDifficulty,
+			Limit:            limit,
+		})
+	default:
+		assignments, err = q.ListAssignmentsByName(ctx, sqlite.ListAssignmentsByNameParams{
+			IncludeSubmitted: query.IncludeSubmitted,
+			CourseID:         query.CourseID,
+			DueAfter:         query.DueAfter,
+			DueBefore:        query.DueBefore,
+			Overdue:          query.Overdue,
+			Now:              now,
+			MinDifficulty:    query.MinDifficulty,
+			HasDueDate:       query.HasDueDate,
+			AfterID:          afterID,
+			AfterName:        afterName,
+			Limit:            limit,
+		})
+	}
+	if err != nil {
+		return assignmentPage{}, fmt.Errorf("failed to list assignments: %v", err)
+	}
+
+	if query.CourseID.Valid {
+		total, err = q.CountCourseAssignments(ctx, sqlite.CountCourseAssignmentsParams{
+			IncludeSubmitted: query.IncludeSubmitted,
+			CourseID:         query.CourseID.Int64,
+			DueAfter:         query.DueAfter,
+			DueBefore:        query.DueBefore,
+			Overdue:          query.Overdue,
+			Now:              now,
+			MinDifficulty:    query.MinDifficulty,
+			HasDueDate:       query.HasDueDate,
+		})
+	} else {
+		total, err = q.CountAssignments(ctx, sqlite.CountAssignmentsParams{
+			IncludeSubmitted: query.IncludeSubmitted,
+			CourseID:         query.CourseID,
+			DueAfter:         query.DueAfter,
+			DueBefore:        query.DueBefore,
+			Overdue:          query.Overdue,
+			Now:              now,
+			MinDifficulty:    query.MinDifficulty,
+			HasDueDate:       query.HasDueDate,
+		})
+	}
+	if err != nil {
+		return assignmentPage{}, fmt.Errorf("failed to count assignments: %v", err)
+	}
+
+	page := assignmentPage{Assignments: assignments, Total: total}
+	if page.Assignments == nil {
+		page.Assignments = []sqlite.Assignment{}
+	}
+	if int64(len(assignments)) > query.Limit {
+		page.Assignments = assignments[:query.Limit]
+		next, err := encodeCursor(cursorAfter(query.Sort, page.Assignments[query.Limit-1]))
+		if err != nil {
+			return assignmentPage{}, err
+		}
+		page.NextCursor = &next
+	}
+	return page, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	due := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	difficulty := int64(7)
+	tests := []assignmentCursor{
+		{Sort: sortDueDate, ID: 12, DueDate: &due},
+		{Sort: sortDueDate, ID: -3},
+		{Sort: sortDifficulty, ID: 4, Difficulty: &difficulty},
+		{Sort: sortDifficulty, ID: 5},
+		{Sort: sortName, ID: 6, Name: "Lab 1: \"Hello\" & <world>"},
+	}
+	for _, cursor := range tests {
+		s, err := encodeCursor(cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := decodeCursor(s)
+		if err != nil {
+			t.Fatalf("decodeCursor(%q): %v", s, err)
+		}
+		if !reflect.DeepEqual(*decoded, cursor) {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v", cursor, *decoded)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, s := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("not json")),
+		base64.RawURLEncoding.EncodeToString([]byte(`{"id": "one"}`)),
+		// Cursors are unpadded
+		base64.URLEncoding.EncodeToString([]byte(`{"sort":"name","id":1}`)),
+	} {
+		if cursor, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) = %+v, want an error", s, cursor)
+		}
+	}
+}
+
+// Parses the query string of a GET /all-assignments
+func parseTestQuery(rawQuery string) (assignmentQuery, error) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/all-assignments?"+rawQuery, nil)
+	return parseAssignmentQuery(c)
+}
+
+func TestParseAssignmentQuery(t *testing.T) {
+	nameCursor, err := encodeCursor(assignmentCursor{Sort: sortName, ID: 1, Name: "Lab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rawQuery string
+		err      string
+	}{
+		{"", ""},
+		{"sort=-difficulty&limit=200&course_id=449&overdue=false&has_due_date=1", ""},
+		{"sort=name&cursor=" + nameCursor, ""},
+		{"sort=difficulty", "sort must be one of due_date, -difficulty or name"},
+		{"limit=0", "limit must be between 1 and 200"},
+		{"limit=201", "limit must be between 1 and 200"},
+		{"limit=ten", "limit must be an integer"},
+		{"course_id=cpsc449", "course_id must be an integer"},
+		{"due_after=2030-01-01", "due_after must be an RFC3339 time"},
+		{"overdue=maybe", "overdue must be true or false"},
+		{"cursor=%21%21", "cursor is invalid"},
+		{"cursor=" + nameCursor, "cursor was made for another sort"},
+	}
+	for _, tt := range tests {
+		_, err := parseTestQuery(tt.rawQuery)
+		if tt.err == "" && err != nil {
+			t.Errorf("parseAssignmentQuery(%q): %v", tt.rawQuery, err)
+		}
+		if tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("parseAssignmentQuery(%q) error = %v, want %q", tt.rawQuery, err, tt.err)
+		}
+	}
+}
+
+// Walks every page of the query, a couple of assignments at a time, and checks the
+// assignments come back in order without any of them repeated or skipped
+func TestListAssignmentsKeyset(t *testing.T) {
+	ctx := context.Background()
+	q := newTestStore(t)
+	if _, err := q.UpsertCourse(ctx, sqlite.UpsertCourseParams{ID: 449, Name: "CPSC 449"}); err != nil {
+		t.Fatal(err)
+	}
+	day := func(d int) sql.NullTime {
+		return sql.NullTime{Time: time.Date(2030, 1, d, 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	difficulty := func(n int64) sql.NullInt64 {
+		return sql.NullInt64{Int64: n, Valid: true}
+	}
+	// Ties on every sort key, and assignments missing a due date or difficulty
+	for _, a := range []sqlite.UpsertAssignmentParams{
+		{ID: 1, Name: "Lab B", DueDate: day(2), Difficulty: difficulty(5)},
+		{ID: 2, Name: "Lab A", DueDate: day(1), Difficulty: difficulty(5)},
+		{ID: 3, Name: "Lab C"},
+		{ID: 4, Name: "Lab A", DueDate: day(1), Difficulty: difficulty(9)},
+		{ID: 5, Name: "Essay", Difficulty: difficulty(2)},
+		{ID: 6, Name: "Quiz", DueDate: day(3)},
+	} {
+		a.CourseID = 449
+		a.Published = true
+		if _, err := q.UpsertAssignment(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		rawQuery string
+		want     []int64
+	}{
+		{"sort=due_date", []int64{2, 4, 1, 6, 3, 5}},
+		{"sort=due_date&course_id=449", []int64{2, 4, 1, 6, 3, 5}},
+		{"sort=-difficulty", []int64{4, 1, 2, 5, 3, 6}},
+		{"sort=name", []int64{5, 2, 4, 1, 3, 6}},
+		{"sort=due_date&has_due_date=true&due_before=2030-01-02T12:00:00Z", []int64{2, 4, 1}},
+		{"sort=-difficulty&min_difficulty=5", []int64{4, 1, 2}},
+		{"sort=name&course_id=0", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rawQuery, func(t *testing.T) {
+			var got []int64
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > len(tt.want) {
+					t.Fatalf("still paging after %d pages, got %v", pages, got)
+				}
+				rawQuery := tt.rawQuery + "&limit=2"
+				if cursor != "" {
+					rawQuery += "&cursor=" + cursor
+				}
+				query, err := parseTestQuery(rawQuery)
+				if err != nil {
+					t.Fatal(err)
+				}
+				page, err := listAssignments(ctx, q, query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if page.Total != int64(len(tt.want)) {
+					t.Errorf("total = %d, want %d", page.Total, len(tt.want))
+				}
+				for _, a := range page.Assignments {
+					got = append(got, a.ID)
+				}
+				if page.NextCursor == nil {
+					break
+				}
+				cursor = *page.NextCursor
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
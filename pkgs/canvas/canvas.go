@@ -1,25 +1,64 @@
 package canvas
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
 )
 
 type CanvasClient struct {
 	BaseURL    string
 	AuthToken  string
 	HTTPClient *http.Client
+	// Enrollment term to use instead of discovering the current one, 0 means discover
+	TermID int
+	// How many times rate limited requests and server errors are retried, and the
+	// delay the exponential backoff starts from
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	// Max number of courses fetched at the same time
+	Concurrency int
+	// Deadline for each request to Canvas (retries included), 0 means no deadline
+	Timeout time.Duration
+
+	throttle *throttle
 }
 
 // https://canvas.instructure.com/doc/api/assignments.html
 type Assignment struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	CourseID    int    `json:"course_id"`
-	Description string `json:"description"`
-	DueAt       string `json:"due_at"`
+	ID                int      `json:"id"`
+	Name              string   `json:"name"`
+	CourseID          int      `json:"course_id"`
+	Description       string   `json:"description"`
+	DueAt             string   `json:"due_at"`
+	UnlockAt          string   `json:"unlock_at"`
+	LockAt            string   `json:"lock_at"`
+	PointsPossible    *float64 `json:"points_possible"`
+	SubmissionTypes   []string `json:"submission_types"`
+	HTMLURL           string   `json:"html_url"`
+	Published         bool     `json:"published"`
+	AssignmentGroupID int      `json:"assignment_group_id"`
+	// The current user's submission, only set when requested with include[]=submission
+	Submission *Submission `json:"submission"`
+}
+
+// https://canvas.instructure.com/doc/api/submissions.html
+type Submission struct {
+	WorkflowState string   `json:"workflow_state"`
+	SubmittedAt   string   `json:"submitted_at"`
+	Score         *float64 `json:"score"`
+	Late          bool     `json:"late"`
+	Missing       bool     `json:"missing"`
+	Excused       bool     `json:"excused"`
 }
 
 type Course struct {
@@ -36,106 +75,400 @@ type Course struct {
 	} `json:"term"`
 }
 
-// NOTE: There might be a way to dynamically get the current term (as of 11/29/24, it'll be Fall 2024)
-// Need to add enrollment_term_id to the url queries tho, but for sake of time, we can hard code it
-// https://canvas.instructure.com/doc/api/enrollment_terms.html#method.terms_api.index
-var CurrentTermID = 15380 // for Fall 2024 at CSUF
-
 func NewCanvasClient(baseURL, authToken string) *CanvasClient {
 	return &CanvasClient{
 		BaseURL:    baseURL,
 		AuthToken:  authToken,
 		HTTPClient: &http.Client{},
+
+		MaxRetries:     defaultMaxRetries,
+		RetryBaseDelay: defaultBaseDelay,
+		Concurrency:    defaultConcurrency,
+		throttle:       &throttle{},
 	}
 }
 
+const defaultConcurrency = 4
+
+// A course along with all of its assignments
+type CourseAssignments struct {
+	Course      Course
+	Assignments []Assignment
+	// ETag of the assignment list, see AssignmentQuery
+	ETag string
+	// Canvas answered 304 Not Modified, Assignments is empty and the ones fetched
+	// with ETag are still current
+	NotModified bool
+}
+
+// Options for listing a course's assignments
+// https://canvas.instructure.com/doc/api/assignments.html#method.assignments_api.index
+type AssignmentQuery struct {
+	// Only list assignments in this bucket (upcoming, future, past, overdue, undated,
+	// ungraded or unsubmitted), empty for all of them
+	Bucket string
+	// ETag from a previous response, makes the request conditional so Canvas can
+	// answer 304 when the list hasn't changed
+	ETag string
+}
+
+// Returned by get when Canvas answers 304 Not Modified
+var errNotModified = errors.New("not modified")
+
+// Same as GetCoursesContext without a context
+func (c *CanvasClient) GetCourses() ([]Course, error) {
+	return c.GetCoursesContext(context.Background())
+}
+
 // https://canvas.instructure.com/doc/api/courses.html#method.courses.index
-func (c *CanvasClient) GetCurrentTermCourses() ([]Course, error) {
-	// Ensure to get all the courses using per_page=100
+func (c *CanvasClient) GetCoursesContext(ctx context.Context) ([]Course, error) {
+	// per_page=100 keeps the number of round trips down, getAllPages follows the rest
 	// https://community.canvaslms.com/t5/Canvas-Developers-Group/Courses-API-request-doesn-t-return-all-courses/m-p/508108
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/courses?published=true&per_page=100&include[]=term", c.BaseURL), nil)
-	if err != nil {
-		return nil, err
-	}
+	return getAllPages[Course](ctx, c, fmt.Sprintf("%s/api/v1/courses?published=true&per_page=100&include[]=term", c.BaseURL))
+}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.AuthToken))
+// Same as GetCourseContext without a context
+func (c *CanvasClient) GetCourse(courseID int) (Course, error) {
+	return c.GetCourseContext(context.Background(), courseID)
+}
+
+// https://canvas.instructure.com/doc/api/courses.html#method.courses.show
+func (c *CanvasClient) GetCourseContext(ctx context.Context, courseID int) (Course, error) {
+	var course Course
+	err := getJSON(ctx, c, fmt.Sprintf("%s/api/v1/courses/%d?include[]=term", c.BaseURL, courseID), &course)
+	return course, err
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// Same as GetCurrentTermCoursesContext without a context
+func (c *CanvasClient) GetCurrentTermCourses() ([]Course, error) {
+	return c.GetCurrentTermCoursesContext(context.Background())
+}
+
+// Returns the courses in the client's TermID, or in the term(s) that are currently
+// running if no term was configured
+func (c *CanvasClient) GetCurrentTermCoursesContext(ctx context.Context) ([]Course, error) {
+	courses, err := c.GetCoursesContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	termIDs := []int{c.TermID}
+	if c.TermID == 0 {
+		termIDs = CurrentTermIDs(courses, time.Now())
 	}
 
-	var courses []Course
-	err = json.Unmarshal(body, &courses)
+	return filterCoursesByTerm(courses, termIDs...), nil
+}
+
+// Same as GetCoursesForTermContext without a context
+func (c *CanvasClient) GetCoursesForTerm(termID int) ([]Course, error) {
+	return c.GetCoursesForTermContext(context.Background(), termID)
+}
+
+func (c *CanvasClient) GetCoursesForTermContext(ctx context.Context, termID int) ([]Course, error) {
+	courses, err := c.GetCoursesContext(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return filterCoursesByTerm(courses, termID), nil
+}
 
-	var currentCourses []Course
+// Works out which enrollment terms are active at the given time using the term dates
+// included with each course. A term with a missing start or end date is treated as open
+// on that side, but terms without any dates (e.g. Canvas's "Default Term") are ignored
+// since they would match every point in time.
+func CurrentTermIDs(courses []Course, now time.Time) []int {
+	var termIDs []int
+	seen := make(map[int]bool)
 
-	// Filter out courses that aren't in the current term
-	for i, course := range courses {
-		if course.Term.ID != CurrentTermID {
+	for _, course := range courses {
+		term := course.Term
+		if seen[term.ID] || (term.StartAt == "" && term.EndAt == "") {
+			continue
+		}
+		seen[term.ID] = true
+
+		if start, err := time.Parse(time.RFC3339, term.StartAt); err == nil && now.Before(start) {
 			continue
 		}
-		currentCourses = append(currentCourses, courses[i])
+		if end, err := time.Parse(time.RFC3339, term.EndAt); err == nil && now.After(end) {
+			continue
+		}
+		termIDs = append(termIDs, term.ID)
 	}
 
-	return currentCourses, nil
+	return termIDs
 }
 
+// Filter out courses that aren't in one of the given terms
+func filterCoursesByTerm(courses []Course, termIDs ...int) []Course {
+	var filtered []Course
+	for i, course := range courses {
+		if !slices.Contains(termIDs, course.Term.ID) {
+			continue
+		}
+		filtered = append(filtered, courses[i])
+	}
+	return filtered
+}
+
+// Same as GetAssignmentsForCourseContext without a context
 func (c *CanvasClient) GetAssignmentsForCourse(courseID int) ([]Assignment, error) {
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/courses/%d/assignments", c.BaseURL, courseID), nil)
+	return c.GetAssignmentsForCourseContext(context.Background(), courseID)
+}
+
+func (c *CanvasClient) GetAssignmentsForCourseContext(ctx context.Context, courseID int) ([]Assignment, error) {
+	return getAllPages[Assignment](ctx, c, c.assignmentsURL(courseID, ""))
+}
+
+// Lists a course's assignments with the given options. The request is conditional
+// when query.ETag is set, and NotModified is set on the result if Canvas says the list
+// is the same. Only the first page carries an ETag, so lists that span multiple pages
+// come back without one since a 304 on the first page says nothing about the rest.
+func (c *CanvasClient) GetAssignmentsForCourseQueryContext(ctx context.Context, course Course, query AssignmentQuery) (CourseAssignments, error) {
+	result := CourseAssignments{Course: course}
+
+	body, header, err := c.get(ctx, c.assignmentsURL(course.ID, query.Bucket), query.ETag)
+	if errors.Is(err, errNotModified) {
+		result.ETag = query.ETag
+		result.NotModified = true
+		return result, nil
+	}
 	if err != nil {
-		return nil, err
+		return result, err
+	}
+	if err := json.Unmarshal(body, &result.Assignments); err != nil {
+		return result, err
 	}
 
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.AuthToken))
+	next := nextPageURL(header.Get("Link"))
+	if next == "" {
+		result.ETag = header.Get("ETag")
+		return result, nil
+	}
+	rest, err := getAllPages[Assignment](ctx, c, next)
+	if err != nil {
+		return result, err
+	}
+	result.Assignments = append(result.Assignments, rest...)
+	return result, nil
+}
 
-	resp, err := c.HTTPClient.Do(req)
+// Assignments are ordered by due date so the list (and its ETag) only changes when the
+// assignments themselves (or the user's submissions) do
+func (c *CanvasClient) assignmentsURL(courseID int, bucket string) string {
+	url := fmt.Sprintf("%s/api/v1/courses/%d/assignments?per_page=100&order_by=due_at&include[]=submission", c.BaseURL, courseID)
+	if bucket != "" {
+		url += "&bucket=" + bucket
+	}
+	return url
+}
+
+// Same as GetAllAssignmentsForCurrentTermContext without a context
+func (c *CanvasClient) GetAllAssignmentsForCurrentTerm() ([]CourseAssignments, error) {
+	return c.GetAllAssignmentsForCurrentTermContext(context.Background())
+}
+
+// Returns the assignments of every course in the current term(s). If some courses
+// fail, the others are still returned along with an error wrapping a *CourseError
+// for each failed course.
+func (c *CanvasClient) GetAllAssignmentsForCurrentTermContext(ctx context.Context) ([]CourseAssignments, error) {
+	courses, err := c.GetCurrentTermCoursesContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return c.GetAssignmentsForCoursesContext(ctx, courses)
+}
+
+// Same as GetAllAssignmentsForTermContext without a context
+func (c *CanvasClient) GetAllAssignmentsForTerm(termID int) ([]CourseAssignments, error) {
+	return c.GetAllAssignmentsForTermContext(context.Background(), termID)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// Same as GetAllAssignmentsForCurrentTerm but for any term, which is useful for
+// backfilling past terms
+func (c *CanvasClient) GetAllAssignmentsForTermContext(ctx context.Context, termID int) ([]CourseAssignments, error) {
+	courses, err := c.GetCoursesForTermContext(ctx, termID)
 	if err != nil {
 		return nil, err
 	}
+	return c.GetAssignmentsForCoursesContext(ctx, courses)
+}
+
+// Same as GetAssignmentsForCoursesContext without a context
+func (c *CanvasClient) GetAssignmentsForCourses(courses []Course) ([]CourseAssignments, error) {
+	return c.GetAssignmentsForCoursesContext(context.Background(), courses)
+}
+
+// Fetches the assignments of the courses concurrently (up to c.Concurrency at a time)
+// and returns them in the same order as the courses. Courses that fail are left out
+// and reported through the returned error, see CourseErrors. An unauthorized error
+// means none of the other courses will work either, so it cancels the ones that
+// haven't started yet.
+func (c *CanvasClient) GetAssignmentsForCoursesContext(ctx context.Context, courses []Course) ([]CourseAssignments, error) {
+	return c.GetAssignmentsForCoursesQueryContext(ctx, courses, nil)
+}
+
+// Same as GetAssignmentsForCoursesContext but with options for each course, keyed by
+// course ID. Courses without an entry get the zero AssignmentQuery.
+func (c *CanvasClient) GetAssignmentsForCoursesQueryContext(ctx context.Context, courses []Course, queries map[int]AssignmentQuery) ([]CourseAssignments, error) {
+	results := make([]*CourseAssignments, len(courses))
+	courseErrs := make([]error, len(courses))
+
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(c.Concurrency, 1))
+
+	// Taken by every worker while the rate limit is running low, so the courses are
+	// fetched one at a time until Canvas has room again
+	var lowRateLimit sync.Mutex
+
+	for i, course := range courses {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				courseErrs[i] = &CourseError{CourseID: course.ID, CourseName: course.Name, Err: err}
+				return nil
+			}
+
+			if c.throttle.low() {
+				lowRateLimit.Lock()
+				defer lowRateLimit.Unlock()
+			}
+
+			result, err := c.GetAssignmentsForCourseQueryContext(ctx, course, queries[course.ID])
+			if err != nil {
+				courseErrs[i] = &CourseError{CourseID: course.ID, CourseName: course.Name, Err: err}
+				if errors.Is(err, ErrUnauthorized) {
+					return err
+				}
+				return nil
+			}
+			results[i] = &result
+			return nil
+		})
+	}
+	g.Wait()
+
+	var allAssignments []CourseAssignments
+	for _, result := range results {
+		if result != nil {
+			allAssignments = append(allAssignments, *result)
+		}
+	}
+	return allAssignments, errors.Join(courseErrs...)
+}
+
+// Canvas paginates every list endpoint (10 items per page by default) and points to
+// the following page through the Link header, so keep requesting until there's no
+// rel="next" left. Any list endpoint should go through here.
+// https://canvas.instructure.com/doc/api/file.pagination.html
+func getAllPages[T any](ctx context.Context, c *CanvasClient, url string) ([]T, error) {
+	var items []T
+	for url != "" {
+		page, next, err := getPage[T](ctx, c, url)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		url = next
+	}
+	return items, nil
+}
 
-	var assignments []Assignment
-	err = json.Unmarshal(body, &assignments)
+// GETs a single (non-list) resource and decodes it into out
+func getJSON(ctx context.Context, c *CanvasClient, url string, out interface{}) error {
+	body, _, err := c.get(ctx, url, "")
 	if err != nil {
-		return nil, err
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// Fetches a single page and returns its items along with the URL of the next page,
+// which is empty on the last page
+func getPage[T any](ctx context.Context, c *CanvasClient, url string) ([]T, string, error) {
+	body, header, err := c.get(ctx, url, "")
+	if err != nil {
+		return nil, "", err
 	}
 
-	return assignments, nil
+	var page []T
+	err = json.Unmarshal(body, &page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return page, nextPageURL(header.Get("Link")), nil
 }
 
-func (c *CanvasClient) GetAllAssignmentsForCurrentTerm() (map[int]map[string][]Assignment, error) {
-	courses, err := c.GetCurrentTermCourses()
+// Sends an authenticated GET request and returns the response body and headers.
+// Requests are spaced out as the rate limit runs low, rate limited requests and
+// server errors are retried with exponential backoff, and any other non-2xx response
+// is returned as an *APIError. The client's Timeout applies to the whole thing,
+// retries included. When etag is set the request is conditional and a 304 response
+// is returned as errNotModified.
+func (c *CanvasClient) get(ctx context.Context, url, etag string) ([]byte, http.Header, error) {
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", c.AuthToken))
+	if etag != "" {
+		req.Header.Add("If-None-Match", etag)
 	}
 
-	allAssignments := make(map[int]map[string][]Assignment)
+	for attempt := 0; ; attempt++ {
+		if err := sleep(ctx, c.throttle.delay()); err != nil {
+			return nil, nil, err
+		}
 
-	for _, course := range courses {
-		assignments, err := c.GetAssignmentsForCourse(course.ID)
+		resp, err := c.HTTPClient.Do(req)
 		if err != nil {
-			fmt.Printf("Error fetching assignments for course %d: %v\n", course.ID, err)
-			continue
+			return nil, nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		c.throttle.update(resp.Header)
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return body, resp.Header, nil
+		}
+		if resp.StatusCode == http.StatusNotModified {
+			return nil, resp.Header, errNotModified
 		}
-		allAssignments[course.ID] = map[string][]Assignment{
-			course.Name: assignments,
+
+		apiErr := newAPIError(resp.StatusCode, body)
+		if !apiErr.retryable() || attempt >= c.MaxRetries {
+			return nil, nil, apiErr
+		}
+		if err := sleep(ctx, backoff(attempt, c.RetryBaseDelay, resp)); err != nil {
+			return nil, nil, err
 		}
 	}
+}
 
-	return allAssignments, nil
+// Parses an RFC 5988 Link header, e.g.
+// <https://x.instructure.com/api/v1/courses?page=2&per_page=10>; rel="next", <...>; rel="last"
+// and returns the URL tagged with rel="next"
+func nextPageURL(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		if len(parts) < 2 {
+			continue
+		}
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			}
+		}
+	}
+	return ""
 }
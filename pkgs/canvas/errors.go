@@ -0,0 +1,113 @@
+package canvas
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Use errors.Is to check which kind of error Canvas returned, errors.As with *APIError
+// gives access to the status code and Canvas's error messages
+var (
+	ErrUnauthorized = errors.New("canvas: unauthorized")
+	ErrRateLimited  = errors.New("canvas: rate limited")
+	ErrNotFound     = errors.New("canvas: not found")
+)
+
+// A non-2xx response from Canvas
+// https://canvas.instructure.com/doc/api/file.throttling.html
+type APIError struct {
+	StatusCode int
+	// Canvas sends either {"errors": [{"message": ...}]} or {"message": ...}
+	// depending on the endpoint
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Errors  []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	// Raw body, used when it isn't JSON
+	Body string `json:"-"`
+
+	kind error
+}
+
+func newAPIError(statusCode int, body []byte) *APIError {
+	e := &APIError{StatusCode: statusCode}
+	if err := json.Unmarshal(body, e); err != nil {
+		e.Body = strings.TrimSpace(string(body))
+	}
+
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		e.kind = ErrRateLimited
+	// Canvas throttles with a 403 rather than a 429
+	case statusCode == http.StatusForbidden && strings.Contains(strings.ToLower(string(body)), "rate limit exceeded"):
+		e.kind = ErrRateLimited
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		e.kind = ErrUnauthorized
+	case statusCode == http.StatusNotFound:
+		e.kind = ErrNotFound
+	}
+	return e
+}
+
+func (e *APIError) Error() string {
+	var messages []string
+	if e.Message != "" {
+		messages = append(messages, e.Message)
+	}
+	for _, m := range e.Errors {
+		messages = append(messages, m.Message)
+	}
+	if len(messages) == 0 && e.Body != "" {
+		messages = append(messages, e.Body)
+	}
+
+	msg := fmt.Sprintf("canvas: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	if len(messages) > 0 {
+		msg += ": " + strings.Join(messages, "; ")
+	}
+	return msg
+}
+
+func (e *APIError) Unwrap() error {
+	return e.kind
+}
+
+// Rate limits and server errors usually go away on their own
+func (e *APIError) retryable() bool {
+	return e.kind == ErrRateLimited || e.StatusCode >= 500
+}
+
+// The assignments of a course couldn't be fetched
+type CourseError struct {
+	CourseID   int
+	CourseName string
+	Err        error
+}
+
+func (e *CourseError) Error() string {
+	return fmt.Sprintf("course %d (%s): %v", e.CourseID, e.CourseName, e.Err)
+}
+
+func (e *CourseError) Unwrap() error {
+	return e.Err
+}
+
+// Returns the *CourseError values wrapped by err, e.g. the one returned by
+// GetAssignmentsForCourses
+func CourseErrors(err error) []*CourseError {
+	switch e := err.(type) {
+	case *CourseError:
+		return []*CourseError{e}
+	case interface{ Unwrap() []error }:
+		var courseErrs []*CourseError
+		for _, inner := range e.Unwrap() {
+			courseErrs = append(courseErrs, CourseErrors(inner)...)
+		}
+		return courseErrs
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package canvas
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Canvas rate limits each token with a leaky bucket: every request costs some amount
+// (X-Request-Cost) and the bucket refills over time. X-Rate-Limit-Remaining tells
+// how much room is left, once it's empty requests start failing with 403s.
+// https://canvas.instructure.com/doc/api/file.throttling.html
+const (
+	// Below this many units remaining, requests start getting spaced out
+	throttleThreshold = 300.0
+	// Wait before each request when the bucket is (almost) empty
+	maxThrottleDelay = 2 * time.Second
+)
+
+// Defaults for retrying rate limited and failed requests
+const (
+	defaultMaxRetries = 5
+	defaultBaseDelay  = 500 * time.Millisecond
+	maxRetryDelay     = 30 * time.Second
+	// Shifting further can overflow, the delay has long hit maxRetryDelay by then anyway
+	maxBackoffShift = 62
+)
+
+// Tracks the rate limit headers of the latest responses. Shared by every request of
+// the client, so concurrent requests slow down together.
+type throttle struct {
+	mu        sync.Mutex
+	known     bool
+	remaining float64
+	cost      float64
+}
+
+func (t *throttle) update(h http.Header) {
+	remaining, err := strconv.ParseFloat(h.Get("X-Rate-Limit-Remaining"), 64)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.known = true
+	t.remaining = remaining
+	if cost, err := strconv.ParseFloat(h.Get("X-Request-Cost"), 64); err == nil {
+		t.cost = cost
+	}
+}
+
+// How long to wait before the next request: nothing while there's plenty of room left,
+// then longer and longer the closer the bucket gets to empty
+func (t *throttle) delay() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.known || t.remaining >= throttleThreshold {
+		return 0
+	}
+	// Not even enough room for another request like the last one
+	if t.remaining <= t.cost {
+		return maxThrottleDelay
+	}
+	return time.Duration(float64(maxThrottleDelay) * (1 - t.remaining/throttleThreshold))
+}
+
+// Whether the bucket is getting low, lets callers reduce how many requests they make
+// at once
+func (t *throttle) low() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.known && t.remaining < throttleThreshold
+}
+
+// Exponential backoff with full jitter, unless Canvas said how long to wait
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func backoff(attempt int, base time.Duration, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, maxRetryDelay)
+		}
+	}
+	// Compared before shifting so a large attempt or base can't overflow the ceiling
+	attempt = min(max(attempt, 0), maxBackoffShift)
+	ceiling := maxRetryDelay
+	if base < maxRetryDelay>>attempt {
+		ceiling = base << attempt
+	}
+	// A base delay of 0 retries right away
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// Sleeps for d, or less if ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,42 @@
+package canvas
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		attempt int
+		base    time.Duration
+		resp    *http.Response
+		max     time.Duration
+	}{
+		{"first attempt", 0, defaultBaseDelay, nil, defaultBaseDelay},
+		{"doubles", 3, defaultBaseDelay, nil, 8 * defaultBaseDelay},
+		{"capped", 10, defaultBaseDelay, nil, maxRetryDelay},
+		{"large attempt", 1000, defaultBaseDelay, nil, maxRetryDelay},
+		{"shift would overflow", 40, time.Hour, nil, maxRetryDelay},
+		{"negative attempt", -1, defaultBaseDelay, nil, defaultBaseDelay},
+		{"no base delay", 3, 0, nil, 0},
+		{"negative base delay", 3, -time.Second, nil, 0},
+		{"retry after", 0, defaultBaseDelay, &http.Response{Header: http.Header{"Retry-After": {"7"}}}, 7 * time.Second},
+		{"retry after capped", 0, defaultBaseDelay, &http.Response{Header: http.Header{"Retry-After": {"3600"}}}, maxRetryDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Jittered, so check the bounds a few times
+			for range 100 {
+				d := backoff(tt.attempt, tt.base, tt.resp)
+				if d < 0 || d > tt.max {
+					t.Fatalf("backoff(%d, %v) = %v, want between 0 and %v", tt.attempt, tt.base, d, tt.max)
+				}
+				if tt.resp != nil && d != tt.max {
+					t.Fatalf("backoff(%d, %v) = %v, want Retry-After's %v", tt.attempt, tt.base, d, tt.max)
+				}
+			}
+		})
+	}
+}
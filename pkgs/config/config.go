@@ -0,0 +1,139 @@
+package config
+
+import (
+	"time"
+
+	"github.com/johncmanuel/cpsc449-project2/pkgs/utils"
+)
+
+// Where the data lives when DATABASE_URL isn't set
+const defaultDatabaseURL = "sqlite:./db/canvas.db"
+
+// All the settings for the service, read from the environment (and .env file)
+type Config struct {
+	Port        string
+	CanvasURL   string
+	CanvasToken string
+	// Enrollment term to sync instead of the current one(s), 0 means discover it
+	// from the course term dates
+	CanvasTermID int
+	// How many times rate limited or failed Canvas requests are retried
+	CanvasMaxRetries int
+	// How many courses are fetched from Canvas at the same time
+	CanvasConcurrency int
+	// Deadline for a single Canvas request, retries included
+	CanvasTimeout time.Duration
+
+	// redis://, rediss:// or unix:// URL of the Redis to use, see redis.Options
+	RedisURL string
+	// Set to go through Redis Sentinel instead
+	RedisSentinelMaster   string
+	RedisSentinelAddrs    []string
+	RedisSentinelPassword string
+	// Set to use a Redis Cluster instead
+	RedisClusterAddrs []string
+	// Connection pool and network settings, 0 keeps the go-redis defaults
+	RedisPoolSize     int
+	RedisDialTimeout  time.Duration
+	RedisReadTimeout  time.Duration
+	RedisWriteTimeout time.Duration
+	// Put in front of every Redis key
+	RedisKeyPrefix string
+	// Deadline for a single Redis operation
+	RedisTimeout time.Duration
+	// What to use when Redis is down, memory (an in-process cache) or none
+	CacheFallback string
+	// Max number of keys in the in-process cache
+	CacheSize int
+	// Size of the in-process cache in front of Redis (0 disables it) and how long keys
+	// stay in it
+	CacheL1Size int
+	CacheL1TTL  time.Duration
+	// Overrides the TTL of each kind of cached entry, see redis.TTLs
+	CacheTTLs map[string]time.Duration
+	// How often Redis is pinged to switch to the fallback and back
+	CacheHealthInterval time.Duration
+
+	// sqlite:<path> or postgres://..., see store.Open
+	DatabaseURL string
+	// Whether pending database migrations are applied on startup, otherwise they're
+	// left to `go run . migrate up`
+	MigrateOnStart bool
+
+	// Time between background Canvas syncs (0 disables them) plus up to SyncJitter
+	SyncInterval  time.Duration
+	SyncJitter    time.Duration
+	SyncOnStartup bool
+	// Max time a replica can hold the sync lock
+	SyncLockTTL time.Duration
+	// Refuse to sync while Redis is down instead of only locking this replica
+	SyncRequireSharedLock bool
+
+	// openai, heuristic, record or replay (see pkgs/llm). Defaults to openai when
+	// there's an API key and to the offline heuristic provider otherwise
+	LLMProvider string
+	// Cassette file for the record/replay providers
+	LLMCassette string
+
+	OpenAIAPIKey string
+	// Lets us point the client at any OpenAI-compatible server, e.g. a local stub
+	OpenAIBaseURL        string
+	OpenAIModel          string
+	OpenAIEmbeddingModel string
+}
+
+func Load() Config {
+	utils.LoadEnv()
+
+	return Config{
+		Port:         utils.GetEnvOrDefault("PORT", "8080"),
+		CanvasURL:    utils.GetEnv("CANVAS_URL"),
+		CanvasToken:  utils.GetEnv("CANVAS_TOKEN"),
+		CanvasTermID: utils.GetEnvInt("CANVAS_TERM_ID", 0),
+
+		CanvasMaxRetries:  utils.GetEnvInt("CANVAS_MAX_RETRIES", 5),
+		CanvasConcurrency: utils.GetEnvInt("CANVAS_CONCURRENCY", 4),
+		CanvasTimeout:     utils.GetEnvDuration("CANVAS_TIMEOUT", time.Minute),
+
+		RedisURL:              utils.GetEnvOrDefault("REDIS_URL", "redis://localhost:6379/0"),
+		RedisSentinelMaster:   utils.GetEnvOrDefault("REDIS_SENTINEL_MASTER", ""),
+		RedisSentinelAddrs:    utils.GetEnvList("REDIS_SENTINEL_ADDRS"),
+		RedisSentinelPassword: utils.GetEnvOrDefault("REDIS_SENTINEL_PASSWORD", ""),
+		RedisClusterAddrs:     utils.GetEnvList("REDIS_CLUSTER_ADDRS"),
+		RedisPoolSize:         utils.GetEnvInt("REDIS_POOL_SIZE", 0),
+		RedisDialTimeout:      utils.GetEnvDuration("REDIS_DIAL_TIMEOUT", 0),
+		RedisReadTimeout:      utils.GetEnvDuration("REDIS_READ_TIMEOUT", 0),
+		RedisWriteTimeout:     utils.GetEnvDuration("REDIS_WRITE_TIMEOUT", 0),
+		RedisKeyPrefix:        utils.GetEnvOrDefault("REDIS_KEY_PREFIX", ""),
+		RedisTimeout:          utils.GetEnvDuration("REDIS_TIMEOUT", 2*time.Second),
+		CacheFallback:         utils.GetEnvOrDefault("CACHE_FALLBACK", "memory"),
+		CacheSize:             utils.GetEnvInt("CACHE_SIZE", 10000),
+		CacheL1Size:           utils.GetEnvInt("CACHE_L1_SIZE", 0),
+		CacheL1TTL:            utils.GetEnvDuration("CACHE_L1_TTL", 5*time.Second),
+		CacheTTLs:             utils.GetEnvDurations("CACHE_TTLS"),
+		CacheHealthInterval:   utils.GetEnvDuration("CACHE_HEALTH_INTERVAL", 5*time.Second),
+
+		DatabaseURL:    utils.GetEnvOrDefault("DATABASE_URL", defaultDatabaseURL),
+		MigrateOnStart: utils.GetEnvBool("MIGRATE_ON_START", true),
+
+		SyncInterval:          utils.GetEnvDuration("SYNC_INTERVAL", time.Hour),
+		SyncJitter:            utils.GetEnvDuration("SYNC_JITTER", 5*time.Minute),
+		SyncOnStartup:         utils.GetEnvBool("SYNC_ON_STARTUP", true),
+		SyncLockTTL:           utils.GetEnvDuration("SYNC_LOCK_TTL", 10*time.Minute),
+		SyncRequireSharedLock: utils.GetEnvBool("SYNC_REQUIRE_SHARED_LOCK", false),
+
+		LLMProvider: utils.GetEnvOrDefault("LLM_PROVIDER", ""),
+		LLMCassette: utils.GetEnvOrDefault("LLM_CASSETTE", "testdata/llm_cassette.json"),
+
+		OpenAIAPIKey:         utils.GetEnvOrDefault("OPENAI_API_KEY", ""),
+		OpenAIBaseURL:        utils.GetEnvOrDefault("OPENAI_BASE_URL", ""),
+		OpenAIModel:          utils.GetEnvOrDefault("OPENAI_MODEL", "gpt-4o-mini"),
+		OpenAIEmbeddingModel: utils.GetEnvOrDefault("OPENAI_EMBEDDING_MODEL", "text-embedding-3-small"),
+	}
+}
+
+// Only reads DATABASE_URL, for the migrate subcommand which doesn't need the rest
+func LoadDatabaseURL() string {
+	utils.LoadEnv()
+	return utils.GetEnvOrDefault("DATABASE_URL", defaultDatabaseURL)
+}
@@ -0,0 +1,225 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Name of the JSON schema used to prioritize assignments. This is the only structured
+// request the heuristic provider knows how to answer: the prompt has to be a JSON list
+// of assignments and the response is {"assignments": [{id, difficulty, length, reason}]}
+// ordered from most to least urgent.
+const PrioritiesSchemaName = "assignment_priorities"
+
+// Number of dimensions of the heuristic embeddings
+const heuristicDimensions = 256
+
+// What the heuristic reads from each assignment in the prompt, anything else is ignored
+type heuristicAssignment struct {
+	ID             json.Number `json:"id"`
+	Name           string      `json:"name"`
+	DueDate        string      `json:"due_date"`
+	PointsPossible float64     `json:"points_possible,omitempty"`
+}
+
+type heuristicEstimate struct {
+	ID         int64  `json:"id"`
+	Difficulty int64  `json:"difficulty"`
+	Length     int64  `json:"length"`
+	Reason     string `json:"reason"`
+}
+
+// Difficulty (1-10) and length (minutes) for assignments whose name contains the keyword.
+// Checked in order so the more specific keywords win, e.g. "Final Project Proposal".
+var keywordEstimates = []struct {
+	keywords   []string
+	difficulty int64
+	length     int64
+}{
+	{[]string{"proposal", "outline", "draft"}, 4, 120},
+	{[]string{"final", "capstone"}, 9, 600},
+	{[]string{"midterm", "exam", "test"}, 8, 360},
+	{[]string{"project"}, 8, 480},
+	{[]string{"paper", "essay", "report", "presentation"}, 7, 360},
+	{[]string{"lab"}, 5, 150},
+	{[]string{"homework", "hw", "assignment", "problem set", "worksheet", "exercise"}, 5, 120},
+	{[]string{"quiz"}, 3, 45},
+	{[]string{"discussion", "reflection", "survey", "attendance", "reading", "participation"}, 2, 30},
+}
+
+// Default estimate for names without any known keyword
+const (
+	defaultDifficulty = 4
+	defaultLength     = 90
+)
+
+// Rule-based provider that needs no network: it estimates difficulty and length from
+// keywords in the assignment name and the points it's worth, then ranks by how much
+// slack is left before the due date. Deterministic, so it's also handy for tests.
+type HeuristicProvider struct {
+	// Used as the reference time for due dates, defaults to time.Now
+	Now func() time.Time
+}
+
+func NewHeuristicProvider() *HeuristicProvider {
+	return &HeuristicProvider{Now: time.Now}
+}
+
+func (p *HeuristicProvider) Name() string {
+	return ProviderHeuristic
+}
+
+func (p *HeuristicProvider) Complete(ctx context.Context, req Request) (string, error) {
+	return "", ErrUnsupported
+}
+
+func (p *HeuristicProvider) CompleteJSON(ctx context.Context, req Request, schema JSONSchema) ([]byte, error) {
+	if schema.Name != PrioritiesSchemaName {
+		return nil, ErrUnsupported
+	}
+
+	var assignments []heuristicAssignment
+	if err := json.Unmarshal([]byte(req.Prompt), &assignments); err != nil {
+		return nil, fmt.Errorf("llm: heuristic provider expects a JSON list of assignments: %v", err)
+	}
+
+	now := p.Now()
+	type ranked struct {
+		estimate heuristicEstimate
+		slack    float64
+	}
+	rankedAssignments := make([]ranked, 0, len(assignments))
+
+	for _, a := range assignments {
+		id, err := a.ID.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("llm: invalid assignment id %q", a.ID)
+		}
+		difficulty, length := EstimateAssignment(a.Name, a.PointsPossible)
+
+		// Minutes left before the due date minus the work needed, assignments without
+		// a due date go last
+		slack := math.Inf(1)
+		reason := fmt.Sprintf("No due date, about %s of work", formatMinutes(length))
+		if due, err := time.Parse(time.RFC3339, a.DueDate); err == nil {
+			left := due.Sub(now)
+			slack = left.Minutes() - float64(length)
+			reason = fmt.Sprintf("Due in %s, about %s of work", formatDuration(left), formatMinutes(length))
+			if left < 0 {
+				reason = fmt.Sprintf("Overdue, about %s of work", formatMinutes(length))
+			}
+		}
+
+		rankedAssignments = append(rankedAssignments, ranked{
+			estimate: heuristicEstimate{
+				ID:         id,
+				Difficulty: difficulty,
+				Length:     length,
+				Reason:     reason,
+			},
+			slack: slack,
+		})
+	}
+
+	sort.SliceStable(rankedAssignments, func(i, j int) bool {
+		return rankedAssignments[i].slack < rankedAssignments[j].slack
+	})
+
+	resp := struct {
+		Assignments []heuristicEstimate `json:"assignments"`
+	}{Assignments: make([]heuristicEstimate, 0, len(rankedAssignments))}
+	for _, r := range rankedAssignments {
+		resp.Assignments = append(resp.Assignments, r.estimate)
+	}
+	return json.Marshal(resp)
+}
+
+// Hashes each word into a fixed-size vector (the "hashing trick") and normalizes it,
+// so texts sharing words end up close to each other
+func (p *HeuristicProvider) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	embeddings := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		vec := make([]float64, heuristicDimensions)
+		for _, word := range words(input) {
+			h := fnv.New32a()
+			h.Write([]byte(word))
+			vec[h.Sum32()%heuristicDimensions]++
+		}
+
+		var norm float64
+		for _, v := range vec {
+			norm += v * v
+		}
+		if norm > 0 {
+			norm = math.Sqrt(norm)
+			for j := range vec {
+				vec[j] /= norm
+			}
+		}
+		embeddings[i] = vec
+	}
+	return embeddings, nil
+}
+
+// Estimates difficulty (1-10) and length (minutes) from the assignment's name and
+// points. Assignments worth a lot of points are assumed to be harder and longer.
+func EstimateAssignment(name string, points float64) (difficulty int64, length int64) {
+	difficulty, length = defaultDifficulty, defaultLength
+
+	lower := " " + strings.Join(words(name), " ") + " "
+	for _, estimate := range keywordEstimates {
+		found := false
+		for _, keyword := range estimate.keywords {
+			if strings.Contains(lower, " "+keyword+" ") {
+				found = true
+				break
+			}
+		}
+		if found {
+			difficulty, length = estimate.difficulty, estimate.length
+			break
+		}
+	}
+
+	switch {
+	case points >= 200:
+		difficulty += 2
+	case points >= 100:
+		difficulty++
+	case points > 0 && points <= 10:
+		difficulty--
+	}
+	if points > 0 {
+		// Scale around a typical 50 point assignment, within reason
+		length = int64(float64(length) * min(max(points/50, 0.5), 3))
+	}
+
+	return min(max(difficulty, 1), 10), length
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func formatMinutes(minutes int64) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%d minutes", minutes)
+	}
+	return fmt.Sprintf("%.1f hours", float64(minutes)/60)
+}
+
+func formatDuration(d time.Duration) string {
+	if d < 48*time.Hour {
+		return fmt.Sprintf("%d hours", int(d.Hours()))
+	}
+	return fmt.Sprintf("%d days", int(d.Hours()/24))
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Returned by providers that can't handle a kind of request, e.g. the heuristic
+// provider can't write free-form text
+var ErrUnsupported = errors.New("llm: request not supported by this provider")
+
+type Request struct {
+	// Instructions for the model
+	System string
+	// The actual input, e.g. a JSON payload
+	Prompt string
+}
+
+// Describes the shape of a structured (JSON) response
+type JSONSchema struct {
+	// Must be a-z, A-Z, 0-9, underscores or dashes, up to 64 characters
+	Name        string
+	Description string
+	Schema      interface{}
+}
+
+type Provider interface {
+	// Name of the provider/model, reported alongside its results
+	Name() string
+	// Returns a free-form text response
+	Complete(ctx context.Context, req Request) (string, error)
+	// Returns a JSON response that follows the given schema
+	CompleteJSON(ctx context.Context, req Request, schema JSONSchema) ([]byte, error)
+	// Returns one embedding vector per input
+	Embed(ctx context.Context, inputs []string) ([][]float64, error)
+}
+
+// Supported values for Options.Provider
+const (
+	ProviderOpenAI    = "openai"
+	ProviderHeuristic = "heuristic"
+	ProviderRecord    = "record"
+	ProviderReplay    = "replay"
+)
+
+type Options struct {
+	// One of the Provider* constants, defaults to openai when there's an API key
+	// and to the heuristic provider otherwise
+	Provider string
+
+	APIKey string
+	// Any OpenAI-compatible server, e.g. http://localhost:11434/v1/
+	BaseURL        string
+	Model          string
+	EmbeddingModel string
+
+	// File used by the record and replay providers
+	CassettePath string
+}
+
+func NewProvider(opts Options) (Provider, error) {
+	name := opts.Provider
+	if name == "" {
+		name = ProviderHeuristic
+		if opts.APIKey != "" {
+			name = ProviderOpenAI
+		}
+	}
+
+	switch name {
+	case ProviderOpenAI:
+		if opts.APIKey == "" && opts.BaseURL == "" {
+			return nil, errors.New("llm: the openai provider needs an API key or a base URL")
+		}
+		return NewOpenAIProvider(opts.APIKey, opts.BaseURL, opts.Model, opts.EmbeddingModel), nil
+	case ProviderHeuristic:
+		return NewHeuristicProvider(), nil
+	case ProviderRecord:
+		// Record whatever the real provider answers so it can be replayed later
+		inner := NewOpenAIProvider(opts.APIKey, opts.BaseURL, opts.Model, opts.EmbeddingModel)
+		return NewRecorder(inner, opts.CassettePath)
+	case ProviderReplay:
+		return NewReplayer(opts.CassettePath)
+	default:
+		return nil, fmt.Errorf("llm: unknown provider %q", name)
+	}
+}
@@ -0,0 +1,108 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Wraps openai-go, works with OpenAI itself or any server implementing the same API
+type OpenAIProvider struct {
+	client         *openai.Client
+	model          string
+	embeddingModel string
+}
+
+func NewOpenAIProvider(apiKey, baseURL, model, embeddingModel string) *OpenAIProvider {
+	opts := []option.RequestOption{option.WithAPIKey(apiKey)}
+	if baseURL != "" {
+		// Paths are resolved relative to the base URL, so it needs the trailing slash
+		opts = append(opts, option.WithBaseURL(strings.TrimSuffix(baseURL, "/")+"/"))
+	}
+	if model == "" {
+		model = openai.ChatModelGPT4oMini
+	}
+	if embeddingModel == "" {
+		embeddingModel = openai.EmbeddingModelTextEmbedding3Small
+	}
+
+	return &OpenAIProvider{
+		client:         openai.NewClient(opts...),
+		model:          model,
+		embeddingModel: embeddingModel,
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return p.model
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req Request) (string, error) {
+	return p.complete(ctx, openai.ChatCompletionNewParams{
+		Messages: openai.F(messages(req)),
+		Model:    openai.F(p.model),
+	})
+}
+
+func (p *OpenAIProvider) CompleteJSON(ctx context.Context, req Request, schema JSONSchema) ([]byte, error) {
+	content, err := p.complete(ctx, openai.ChatCompletionNewParams{
+		Messages: openai.F(messages(req)),
+		ResponseFormat: openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](
+			openai.ResponseFormatJSONSchemaParam{
+				Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+				JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:        openai.F(schema.Name),
+					Description: openai.F(schema.Description),
+					Schema:      openai.F(schema.Schema),
+					Strict:      openai.Bool(true),
+				}),
+			},
+		),
+		Model: openai.F(p.model),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(content), nil
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.F[openai.EmbeddingNewParamsInputUnion](openai.EmbeddingNewParamsInputArrayOfStrings(inputs)),
+		Model: openai.F(p.embeddingModel),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float64, len(inputs))
+	for _, e := range resp.Data {
+		if e.Index < 0 || int(e.Index) >= len(embeddings) {
+			return nil, errors.New("llm: embedding index out of range")
+		}
+		embeddings[e.Index] = e.Embedding
+	}
+	return embeddings, nil
+}
+
+func (p *OpenAIProvider) complete(ctx context.Context, params openai.ChatCompletionNewParams) (string, error) {
+	chat, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	if len(chat.Choices) == 0 {
+		return "", errors.New("llm: model returned no choices")
+	}
+	return chat.Choices[0].Message.Content, nil
+}
+
+func messages(req Request) []openai.ChatCompletionMessageParamUnion {
+	var msgs []openai.ChatCompletionMessageParamUnion
+	if req.System != "" {
+		msgs = append(msgs, openai.SystemMessage(req.System))
+	}
+	return append(msgs, openai.UserMessage(req.Prompt))
+}
@@ -0,0 +1,165 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// A recorded request and the response the provider gave to it
+type interaction struct {
+	Key      string          `json:"key"`
+	Method   string          `json:"method"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+type cassette struct {
+	Provider     string        `json:"provider"`
+	Interactions []interaction `json:"interactions"`
+}
+
+// Records the responses of another provider to a cassette file (recording mode) or
+// plays them back without any network access (replay mode). Requests are matched by
+// their exact content, so replaying only works for requests that were recorded.
+type RecordReplayProvider struct {
+	inner Provider // nil when replaying
+	path  string
+
+	mu       sync.Mutex
+	cassette cassette
+	byKey    map[string]json.RawMessage
+}
+
+// Passes requests through to inner and saves every response to the cassette at path
+func NewRecorder(inner Provider, path string) (*RecordReplayProvider, error) {
+	p, err := loadCassette(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	p.inner = inner
+	p.cassette.Provider = inner.Name()
+	return p, nil
+}
+
+// Answers requests from the cassette at path, which must exist
+func NewReplayer(path string) (*RecordReplayProvider, error) {
+	return loadCassette(path)
+}
+
+func loadCassette(path string) (*RecordReplayProvider, error) {
+	if path == "" {
+		return nil, errors.New("llm: the record/replay provider needs a cassette path")
+	}
+	p := &RecordReplayProvider{
+		path:  path,
+		byKey: make(map[string]json.RawMessage),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, fmt.Errorf("llm: failed to read cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &p.cassette); err != nil {
+		return nil, fmt.Errorf("llm: failed to parse cassette: %v", err)
+	}
+	for _, i := range p.cassette.Interactions {
+		p.byKey[i.Key] = i.Response
+	}
+	return p, nil
+}
+
+func (p *RecordReplayProvider) Name() string {
+	return p.cassette.Provider
+}
+
+func (p *RecordReplayProvider) Complete(ctx context.Context, req Request) (string, error) {
+	var resp string
+	err := p.do("complete", req, &resp, func() (interface{}, error) {
+		return p.inner.Complete(ctx, req)
+	})
+	return resp, err
+}
+
+func (p *RecordReplayProvider) CompleteJSON(ctx context.Context, req Request, schema JSONSchema) ([]byte, error) {
+	key := struct {
+		Request Request
+		Schema  JSONSchema
+	}{req, schema}
+
+	var resp json.RawMessage
+	err := p.do("complete_json", key, &resp, func() (interface{}, error) {
+		content, err := p.inner.CompleteJSON(ctx, req, schema)
+		return json.RawMessage(content), err
+	})
+	return resp, err
+}
+
+func (p *RecordReplayProvider) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	var resp [][]float64
+	err := p.do("embed", inputs, &resp, func() (interface{}, error) {
+		return p.inner.Embed(ctx, inputs)
+	})
+	return resp, err
+}
+
+// Looks up the response for the request, or (when recording) gets it from the inner
+// provider and saves it. The response is decoded into out either way.
+func (p *RecordReplayProvider) do(method string, req interface{}, out interface{}, call func() (interface{}, error)) error {
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(append([]byte(method+":"), reqJSON...))
+	key := hex.EncodeToString(sum[:])
+
+	p.mu.Lock()
+	recorded, ok := p.byKey[key]
+	p.mu.Unlock()
+	if ok {
+		return json.Unmarshal(recorded, out)
+	}
+	if p.inner == nil {
+		return fmt.Errorf("llm: no recorded response for %s request %s", method, key[:12])
+	}
+
+	resp, err := call()
+	if err != nil {
+		return err
+	}
+	respJSON, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.byKey[key] = respJSON
+	p.cassette.Interactions = append(p.cassette.Interactions, interaction{
+		Key:      key,
+		Method:   method,
+		Request:  reqJSON,
+		Response: respJSON,
+	})
+	if err := p.save(); err != nil {
+		return err
+	}
+	return json.Unmarshal(respJSON, out)
+}
+
+func (p *RecordReplayProvider) save() error {
+	data, err := json.MarshalIndent(p.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, data, 0o644)
+}
@@ -0,0 +1,109 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// Answers from memory and counts how often it was asked
+type fakeProvider struct {
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Complete(ctx context.Context, req Request) (string, error) {
+	p.calls++
+	return "echo: " + req.Prompt, nil
+}
+
+func (p *fakeProvider) CompleteJSON(ctx context.Context, req Request, schema JSONSchema) ([]byte, error) {
+	p.calls++
+	return []byte(`{"schema":"` + schema.Name + `"}`), nil
+}
+
+func (p *fakeProvider) Embed(ctx context.Context, inputs []string) ([][]float64, error) {
+	p.calls++
+	vectors := make([][]float64, len(inputs))
+	for i, input := range inputs {
+		vectors[i] = []float64{float64(len(input)), 1}
+	}
+	return vectors, nil
+}
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cassettes", "llm.json")
+	inner := &fakeProvider{}
+
+	recorder, err := NewRecorder(inner, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := Request{System: "be brief", Prompt: "hello"}
+	schema := JSONSchema{Name: "greeting"}
+	if _, err := recorder.Complete(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.CompleteJSON(ctx, req, schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorder.Embed(ctx, []string{"abc"}); err != nil {
+		t.Fatal(err)
+	}
+	// Asking again is answered from the cassette
+	if _, err := recorder.Complete(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if inner.calls != 3 {
+		t.Errorf("inner provider was called %d times, want 3", inner.calls)
+	}
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := replayer.Name(); name != "fake" {
+		t.Errorf("Name() = %q, want the recorded provider", name)
+	}
+	text, err := replayer.Complete(ctx, req)
+	if err != nil || text != "echo: hello" {
+		t.Errorf("Complete() = %q, %v", text, err)
+	}
+	// The cassette is indented when it's saved, so only the JSON is the same
+	content, err := replayer.CompleteJSON(ctx, req, schema)
+	var decoded map[string]string
+	if err == nil {
+		err = json.Unmarshal(content, &decoded)
+	}
+	if err != nil || decoded["schema"] != "greeting" {
+		t.Errorf("CompleteJSON() = %s, %v", content, err)
+	}
+	vectors, err := replayer.Embed(ctx, []string{"abc"})
+	if err != nil || len(vectors) != 1 || vectors[0][0] != 3 {
+		t.Errorf("Embed() = %v, %v", vectors, err)
+	}
+
+	// Anything that differs from what was recorded, even just the schema, isn't found
+	if _, err := replayer.Complete(ctx, Request{System: "be brief", Prompt: "hello!"}); err == nil {
+		t.Error("Complete() of an unrecorded prompt succeeded")
+	}
+	if _, err := replayer.CompleteJSON(ctx, req, JSONSchema{Name: "other"}); err == nil {
+		t.Error("CompleteJSON() with an unrecorded schema succeeded")
+	}
+}
+
+func TestNewReplayerMissingCassette(t *testing.T) {
+	if _, err := NewReplayer(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("NewReplayer() of a missing cassette succeeded")
+	}
+	if _, err := NewReplayer(""); err == nil {
+		t.Error("NewReplayer() without a path succeeded")
+	}
+	// Recording starts a new cassette instead
+	if _, err := NewRecorder(&fakeProvider{}, filepath.Join(t.TempDir(), "new.json")); err != nil {
+		t.Errorf("NewRecorder() of a new cassette = %v", err)
+	}
+}
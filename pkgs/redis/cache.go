@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Returned by Get when the key isn't cached, the same error go-redis returns so
+// callers don't need to care which cache they're talking to
+var ErrNotFound = redis.Nil
+
+// How long Set keeps a value around
+const defaultExpiration = 2 * time.Minute
+
+// Names of the caches, see Cache.Name
+const (
+	CacheRedis  = "redis"
+	CacheTiered = "redis+memory"
+	CacheMemory = "memory"
+	CacheNone   = "none"
+)
+
+// The operations the service needs from a cache, implemented by RedisClient (and the
+// TieredCache in front of it), by the in-process MemoryCache and NoopCache it falls
+// back to when Redis is down and by the FailoverCache switching between them
+type Cache interface {
+	// One of the Cache* constants, reported by the health endpoint
+	Name() string
+	// Whether the other replicas see what's stored and the locks, false for the
+	// in-process caches
+	Shared() bool
+	Ping(ctx context.Context) error
+
+	// Values are stored as JSON unless they're []byte, and read back as a string.
+	// SetContext uses the default expiration, 0 means the key never expires.
+	GetContext(ctx context.Context, key string) (string, error)
+	SetContext(ctx context.Context, key string, value interface{}) error
+	SetWithExpirationContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	ExistsContext(ctx context.Context, key string) (bool, error)
+	DeleteContext(ctx context.Context, key string) error
+	IncrementContext(ctx context.Context, key string) (int64, error)
+
+	SetHashContext(ctx context.Context, key string, fields map[string]interface{}) error
+	GetHashContext(ctx context.Context, key string) (map[string]string, error)
+
+	// Evicts the entries an entity change made stale, on every replica
+	InvalidateContext(ctx context.Context, inv Invalidation) error
+
+	AcquireLockContext(ctx context.Context, key string, ttl time.Duration) (string, bool, error)
+	ReleaseLockContext(ctx context.Context, key, token string) error
+}
+
+var (
+	_ Cache = (*RedisClient)(nil)
+	_ Cache = (*MemoryCache)(nil)
+	_ Cache = NoopCache{}
+	_ Cache = (*TieredCache)(nil)
+	_ Cache = (*FailoverCache)(nil)
+)
+
+// What to use instead of Redis when it can't be reached
+var (
+	// CacheMemory or CacheNone
+	CACHE_FALLBACK = CacheMemory
+	// Max number of keys the in-process cache holds
+	CACHE_SIZE = 10000
+	// Max number of keys in the in-process cache in front of Redis, 0 disables it
+	CACHE_L1_SIZE = 0
+	// Max time a key stays in the in-process cache in front of Redis
+	CACHE_L1_TTL = 5 * time.Second
+	// How often Redis is pinged to switch to the fallback when it's down, and back when
+	// it answers again
+	CACHE_HEALTH_INTERVAL = 5 * time.Second
+)
+
+// The cache shared across the service, picked the first time GetInstance is called
+// unless SetInstance was called before
+var (
+	instance Cache
+	mu       sync.Mutex
+)
+
+func GetInstance() Cache {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if instance == nil {
+		instance = Open(context.Background())
+	}
+	return instance
+}
+
+// Replaces the shared cache, e.g. with a MemoryCache in tests
+func SetInstance(c Cache) {
+	mu.Lock()
+	defer mu.Unlock()
+	instance = c
+}
+
+// Connects to the Redis in REDIS_OPTIONS (with an in-process cache in front of it when
+// CACHE_L1_SIZE is set), switching to the CACHE_FALLBACK cache whenever it's down. Only
+// settings Redis can never be reached with leave the fallback for good.
+func Open(ctx context.Context) Cache {
+	var fallback Cache = NewMemoryCache(CACHE_SIZE)
+	if CACHE_FALLBACK == CacheNone {
+		fallback = NewNoopCache()
+	}
+
+	r, err := NewRedisClient(REDIS_OPTIONS)
+	if err != nil {
+		fmt.Printf("%v, using the %s cache\n", err, fallback.Name())
+		return fallback
+	}
+	ctx = context.WithoutCancel(ctx)
+	var primary Cache = r
+	if CACHE_L1_SIZE > 0 {
+		primary = NewTieredCache(ctx, r, CACHE_L1_SIZE, CACHE_L1_TTL)
+	}
+
+	healthy := true
+	if err := r.Ping(ctx); err != nil {
+		healthy = false
+		fmt.Printf("Failed to connect to Redis (%v), using the %s cache until it answers\n", err, fallback.Name())
+	}
+	return NewFailoverCache(ctx, primary, fallback, healthy, CACHE_HEALTH_INTERVAL)
+}
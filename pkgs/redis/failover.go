@@ -0,0 +1,190 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Max number of invalidations remembered while Redis is down, see FailoverCache
+const maxPendingInvalidations = 1000
+
+// Redis (or the TieredCache in front of it) while it answers, and the fallback cache
+// while it doesn't. Redis is pinged every interval, so a Redis that goes away at
+// runtime stops slowing down every request and one that comes back is used again.
+//
+// The invalidations Redis missed while it was down are replayed on it before it's used
+// again, so it doesn't hand out what was changed in the meantime. The fallback is
+// emptied whenever Redis goes down, since it missed the invalidations made while Redis
+// was up.
+type FailoverCache struct {
+	primary  Cache
+	fallback Cache
+
+	mu      sync.RWMutex
+	healthy bool
+	// Invalidations to replay on primary once it's back, overflowed is set when there
+	// were too many to remember
+	pending    []Invalidation
+	overflowed bool
+	// Tokens of the locks taken from fallback, released there even if primary is back
+	fallbackLocks map[string]bool
+}
+
+// Uses primary while it answers pings and fallback otherwise, checking it every
+// interval until ctx is done. healthy says whether primary answered the last ping.
+func NewFailoverCache(ctx context.Context, primary, fallback Cache, healthy bool, interval time.Duration) *FailoverCache {
+	f := &FailoverCache{
+		primary:       primary,
+		fallback:      fallback,
+		healthy:       healthy,
+		fallbackLocks: make(map[string]bool),
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				f.check(ctx)
+			}
+		}
+	}()
+	return f
+}
+
+// Pings primary and switches to (or away from) it
+func (f *FailoverCache) check(ctx context.Context) {
+	err := f.primary.Ping(ctx)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case err != nil && f.healthy:
+		f.healthy = false
+		if m, ok := f.fallback.(*MemoryCache); ok {
+			m.flush()
+		}
+		fmt.Printf("%s cache stopped answering (%v), falling back to the %s cache\n", f.primary.Name(), err, f.fallback.Name())
+
+	case err == nil:
+		for len(f.pending) > 0 {
+			if err := f.primary.InvalidateContext(ctx, f.pending[0]); err != nil {
+				fmt.Printf("Error replaying invalidations on the %s cache: %v\n", f.primary.Name(), err)
+				return
+			}
+			f.pending = f.pending[1:]
+		}
+		if f.overflowed {
+			fmt.Printf("Missed more than %d invalidations while the %s cache was down, its entries may be stale until they expire\n",
+				maxPendingInvalidations, f.primary.Name())
+			f.overflowed = false
+		}
+		if !f.healthy {
+			f.healthy = true
+			fmt.Printf("%s cache is back\n", f.primary.Name())
+		}
+	}
+}
+
+// The cache to use right now
+func (f *FailoverCache) current() Cache {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.healthy {
+		return f.primary
+	}
+	return f.fallback
+}
+
+func (f *FailoverCache) Name() string {
+	return f.current().Name()
+}
+
+func (f *FailoverCache) Shared() bool {
+	return f.current().Shared()
+}
+
+func (f *FailoverCache) Ping(ctx context.Context) error {
+	return f.current().Ping(ctx)
+}
+
+func (f *FailoverCache) GetContext(ctx context.Context, key string) (string, error) {
+	return f.current().GetContext(ctx, key)
+}
+
+func (f *FailoverCache) SetContext(ctx context.Context, key string, value interface{}) error {
+	return f.current().SetContext(ctx, key, value)
+}
+
+func (f *FailoverCache) SetWithExpirationContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return f.current().SetWithExpirationContext(ctx, key, value, expiration)
+}
+
+func (f *FailoverCache) ExistsContext(ctx context.Context, key string) (bool, error) {
+	return f.current().ExistsContext(ctx, key)
+}
+
+func (f *FailoverCache) DeleteContext(ctx context.Context, key string) error {
+	return f.InvalidateContext(ctx, Invalidation{Keys: []string{key}})
+}
+
+func (f *FailoverCache) IncrementContext(ctx context.Context, key string) (int64, error) {
+	return f.current().IncrementContext(ctx, key)
+}
+
+func (f *FailoverCache) SetHashContext(ctx context.Context, key string, fields map[string]interface{}) error {
+	return f.current().SetHashContext(ctx, key, fields)
+}
+
+func (f *FailoverCache) GetHashContext(ctx context.Context, key string) (map[string]string, error) {
+	return f.current().GetHashContext(ctx, key)
+}
+
+// Remembers the invalidations primary missed, to replay them once it answers again
+func (f *FailoverCache) InvalidateContext(ctx context.Context, inv Invalidation) error {
+	if c := f.current(); c == f.primary {
+		err := c.InvalidateContext(ctx, inv)
+		if err != nil {
+			f.remember(inv)
+		}
+		return err
+	}
+	f.remember(inv)
+	return f.fallback.InvalidateContext(ctx, inv)
+}
+
+func (f *FailoverCache) remember(inv Invalidation) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.pending) < maxPendingInvalidations {
+		f.pending = append(f.pending, inv)
+	} else {
+		f.overflowed = true
+	}
+}
+
+func (f *FailoverCache) AcquireLockContext(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	c := f.current()
+	token, ok, err := c.AcquireLockContext(ctx, key, ttl)
+	if ok && c == f.fallback {
+		f.mu.Lock()
+		f.fallbackLocks[token] = true
+		f.mu.Unlock()
+	}
+	return token, ok, err
+}
+
+func (f *FailoverCache) ReleaseLockContext(ctx context.Context, key, token string) error {
+	f.mu.Lock()
+	fromFallback := f.fallbackLocks[token]
+	delete(f.fallbackLocks, token)
+	f.mu.Unlock()
+	if fromFallback {
+		return f.fallback.ReleaseLockContext(ctx, key, token)
+	}
+	return f.primary.ReleaseLockContext(ctx, key, token)
+}
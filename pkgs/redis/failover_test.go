@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Stands in for Redis, going down and coming back when told to
+type fakeRedis struct {
+	*MemoryCache
+	down bool
+}
+
+func (r *fakeRedis) Name() string {
+	return CacheRedis
+}
+
+func (r *fakeRedis) Shared() bool {
+	return true
+}
+
+func (r *fakeRedis) Ping(ctx context.Context) error {
+	if r.down {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestFailoverCache(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	primary := &fakeRedis{MemoryCache: NewMemoryCache(100)}
+	fallback := NewMemoryCache(100)
+	// The checks are run by hand
+	f := NewFailoverCache(ctx, primary, fallback, true, time.Hour)
+
+	if err := f.SetContext(ctx, "assignment", "Lab 1"); err != nil {
+		t.Fatal(err)
+	}
+	if f.Name() != CacheRedis || !f.Shared() {
+		t.Errorf("cache = %s (shared %v), want redis", f.Name(), f.Shared())
+	}
+
+	// Redis goes down: the fallback takes over and the lock only covers this process
+	primary.down = true
+	f.check(ctx)
+	if f.Name() != CacheMemory || f.Shared() {
+		t.Errorf("cache while Redis is down = %s (shared %v), want memory", f.Name(), f.Shared())
+	}
+	if _, err := f.GetContext(ctx, "assignment"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get from the fallback = %v, want ErrNotFound", err)
+	}
+	if err := f.DeleteContext(ctx, "assignment"); err != nil {
+		t.Fatal(err)
+	}
+	token, ok, err := f.AcquireLockContext(ctx, "lock:sync", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("acquiring the lock from the fallback = %v, %v", ok, err)
+	}
+
+	// Redis is back: the delete it missed is replayed before it's used again
+	primary.down = false
+	f.check(ctx)
+	if f.Name() != CacheRedis {
+		t.Errorf("cache once Redis is back = %s, want redis", f.Name())
+	}
+	if _, err := f.GetContext(ctx, "assignment"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("get of the key deleted while Redis was down = %v, want ErrNotFound", err)
+	}
+
+	// The lock goes back to where it came from
+	if err := f.ReleaseLockContext(ctx, "lock:sync", token); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := fallback.AcquireLockContext(ctx, "lock:sync", time.Minute); !ok {
+		t.Error("the fallback's lock is still held after releasing it")
+	}
+}
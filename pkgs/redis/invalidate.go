@@ -0,0 +1,217 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Channel every replica listens on for cache entries to evict
+const invalidationChannel = namespace + ":invalidate"
+
+// Cached entries to evict: exact keys, and prefixes matching every key that starts
+// with them
+type Invalidation struct {
+	Keys     []string `json:"keys,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// Message published on invalidationChannel, Origin lets a replica skip its own
+type invalidationMessage struct {
+	Origin string `json:"origin"`
+	Invalidation
+}
+
+func (i Invalidation) matches(key string) bool {
+	for _, k := range i.Keys {
+		if k == key {
+			return true
+		}
+	}
+	for _, prefix := range i.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// What goes stale when an assignment changes: the assignment, its course (which
+// counts it) and every list it shows up in
+func AssignmentInvalidation(courseID, assignmentID int64) Invalidation {
+	return Invalidation{
+		Keys: []string{
+			Key(KindAssignment, courseID, assignmentID),
+			Key(KindCourse, courseID),
+			Key(KindCourseAssignments, courseID),
+		},
+		Prefixes: []string{KeyPrefix(KindAssignmentList)},
+	}
+}
+
+// What goes stale when a course or any number of its assignments change
+func CourseInvalidation(courseID int64) Invalidation {
+	return Invalidation{
+		Keys: []string{
+			Key(KindCourse, courseID),
+			Key(KindCourseAssignments, courseID),
+			Key(KindCourseList),
+		},
+		Prefixes: []string{
+			KeyPrefix(KindAssignment, courseID),
+			KeyPrefix(KindAssignmentList),
+		},
+	}
+}
+
+// What goes stale when every assignment may have changed, e.g. new estimates
+func AllAssignmentsInvalidation() Invalidation {
+	return Invalidation{
+		Prefixes: []string{
+			KeyPrefix(KindAssignment),
+			KeyPrefix(KindAssignmentList),
+		},
+	}
+}
+
+// What goes stale when a sync run starts or finishes
+func SyncRunsInvalidation() Invalidation {
+	return Invalidation{
+		Prefixes: []string{KeyPrefix(KindSyncRuns)},
+	}
+}
+
+// Deletes the keys from Redis and publishes the invalidation, so the replicas with an
+// in-process cache in front of Redis evict them too
+func (r *RedisClient) InvalidateContext(ctx context.Context, inv Invalidation) error {
+	return r.invalidate(ctx, "", inv)
+}
+
+func (r *RedisClient) invalidate(ctx context.Context, origin string, inv Invalidation) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+
+	keys := make([]string, 0, len(inv.Keys))
+	for _, key := range inv.Keys {
+		keys = append(keys, r.key(key))
+	}
+	for _, prefix := range inv.Prefixes {
+		matched, err := r.scan(ctx, r.key(prefix))
+		if err != nil {
+			return fmt.Errorf("failed to scan for %s: %v", prefix, err)
+		}
+		keys = append(keys, matched...)
+	}
+
+	// One DEL per key, a cluster can't delete keys from different slots at once
+	if len(keys) > 0 {
+		pipe := r.client.Pipeline()
+		for _, key := range keys {
+			pipe.Del(ctx, key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to delete keys: %v", err)
+		}
+	}
+
+	msg, err := json.Marshal(invalidationMessage{Origin: origin, Invalidation: inv})
+	if err != nil {
+		return err
+	}
+	if err := r.client.Publish(ctx, r.key(invalidationChannel), msg).Err(); err != nil {
+		return fmt.Errorf("failed to publish invalidation: %v", err)
+	}
+	return nil
+}
+
+// Lists the keys starting with prefix, on every master of a cluster
+func (r *RedisClient) scan(ctx context.Context, prefix string) ([]string, error) {
+	match := escapeGlob(prefix) + "*"
+	scanNode := func(ctx context.Context, client *redis.Client) ([]string, error) {
+		var keys []string
+		iter := client.Scan(ctx, 0, match, 100).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		return keys, iter.Err()
+	}
+
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		client, ok := r.client.(*redis.Client)
+		if !ok {
+			return nil, fmt.Errorf("can't scan a %T", r.client)
+		}
+		return scanNode(ctx, client)
+	}
+
+	var (
+		keys []string
+		mu   sync.Mutex
+	)
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		found, err := scanNode(ctx, client)
+		mu.Lock()
+		keys = append(keys, found...)
+		mu.Unlock()
+		return err
+	})
+	return keys, err
+}
+
+// Escapes the characters SCAN's MATCH treats as a pattern
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Calls evict for every invalidation another replica publishes, until ctx is done.
+// onResubscribe is called whenever the subscription comes back after a disconnect,
+// since anything published in the meantime was missed.
+func (r *RedisClient) subscribeInvalidations(ctx context.Context, origin string, evict func(Invalidation), onResubscribe func()) {
+	pubsub := r.client.Subscribe(ctx, r.key(invalidationChannel))
+	defer pubsub.Close()
+
+	subscribed := false
+	ch := pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			switch msg := msg.(type) {
+			case *redis.Subscription:
+				if msg.Kind != "subscribe" {
+					continue
+				}
+				if subscribed {
+					onResubscribe()
+				}
+				subscribed = true
+			case *redis.Message:
+				var inv invalidationMessage
+				if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+					fmt.Printf("Error unmarshalling invalidation: %v\n", err)
+					continue
+				}
+				if inv.Origin != "" && inv.Origin == origin {
+					continue
+				}
+				evict(inv.Invalidation)
+			}
+		}
+	}
+}
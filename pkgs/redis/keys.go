@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Every key starts with the namespace and the schema version, e.g.
+// c449:v3:assignment:{course}:{id}
+const namespace = "c449"
+
+// Bump whenever a cached type changes shape (e.g. sqlite.Assignment gains a column),
+// every entry cached by the previous version is ignored from then on and expires on
+// its own
+const SchemaVersion = 3
+
+// The types of entities we cache, each with its own TTL
+type Kind string
+
+const (
+	KindAssignment        Kind = "assignment"
+	KindAssignmentList    Kind = "assignment_list"
+	KindCourse            Kind = "course"
+	KindCourseList        Kind = "course_list"
+	KindCourseAssignments Kind = "course_assignments"
+	KindSyncRuns          Kind = "sync_runs"
+	KindPlan              Kind = "plan"
+	KindSyncJob           Kind = "sync_job"
+)
+
+// How long each kind of entry is cached, 0 means it never expires. Kinds that aren't
+// listed get the default expiration.
+var TTLs = map[Kind]time.Duration{
+	KindAssignment:        2 * time.Minute,
+	KindAssignmentList:    time.Minute,
+	KindCourse:            5 * time.Minute,
+	KindCourseList:        5 * time.Minute,
+	KindCourseAssignments: 2 * time.Minute,
+	KindSyncRuns:          30 * time.Second,
+	// The latest plan stays available until a new one is generated
+	KindPlan: 0,
+	// How long finished sync jobs can be polled for
+	KindSyncJob: 24 * time.Hour,
+}
+
+// Builds the versioned key of an entity, e.g. Key(KindAssignment, 123, 456) is
+// c449:v3:assignment:123:456
+func Key(kind Kind, parts ...interface{}) string {
+	key := fmt.Sprintf("%s:v%d:%s", namespace, SchemaVersion, kind)
+	for _, part := range parts {
+		key += fmt.Sprintf(":%v", part)
+	}
+	return key
+}
+
+// Builds the prefix shared by the keys of a kind (and parts), e.g.
+// KeyPrefix(KindAssignment, 123) is c449:v3:assignment:123: which matches every cached
+// assignment of course 123
+func KeyPrefix(kind Kind, parts ...interface{}) string {
+	return Key(kind, parts...) + ":"
+}
+
+// Builds the key of a lock. Locks aren't versioned so replicas running different
+// versions during a deploy still exclude each other.
+func LockKey(name string) string {
+	return fmt.Sprintf("%s:lock:%s", namespace, name)
+}
+
+func (k Kind) TTL() time.Duration {
+	if ttl, ok := TTLs[k]; ok {
+		return ttl
+	}
+	return defaultExpiration
+}
+
+// Overrides the TTL of some kinds, e.g. from CACHE_TTLS
+func SetTTLs(ttls map[string]time.Duration) error {
+	for name, ttl := range ttls {
+		kind := Kind(name)
+		if _, ok := TTLs[kind]; !ok {
+			return fmt.Errorf("unknown cache kind %q, expected one of %s", name, kindNames())
+		}
+		if ttl < 0 {
+			return fmt.Errorf("negative TTL for %s", name)
+		}
+		TTLs[kind] = ttl
+	}
+	return nil
+}
+
+func kindNames() string {
+	names := make([]string, 0, len(TTLs))
+	for kind := range TTLs {
+		names = append(names, string(kind))
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
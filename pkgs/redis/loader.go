@@ -0,0 +1,192 @@
+package redis
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// How GetOrLoad caches what it loads
+type LoadOptions struct {
+	// How long a loaded value is fresh, defaults to the kind's TTL
+	TTL time.Duration
+	// How much longer a value is still served after it stops being fresh, while it's
+	// reloaded in the background
+	StaleWhileRevalidate time.Duration
+	// How long a sql.ErrNoRows from the loader is remembered so repeated 404s don't
+	// reach the database, 0 doesn't remember them
+	NegativeTTL time.Duration
+	// Holds a lock in the cache while loading, so only one replica loads a missing
+	// value and the others wait for it to be cached
+	Lock bool
+}
+
+// What GetOrLoad stores under a key
+type loadedEntry[T any] struct {
+	Value    T    `json:"value"`
+	NotFound bool `json:"not_found,omitempty"`
+	// When the value stops being fresh, zero if it never does
+	FreshUntil time.Time `json:"fresh_until"`
+}
+
+func (e loadedEntry[T]) stale(now time.Time) bool {
+	return !e.FreshUntil.IsZero() && now.After(e.FreshUntil)
+}
+
+// Concurrent misses of the same key share a single load, and a stale key is only
+// refreshed by one goroutine at a time
+var (
+	loads      singleflight.Group
+	refreshing sync.Map
+)
+
+// How long a replica waits for another one holding the load lock before loading the
+// value itself
+const (
+	loadLockTTL      = 10 * time.Second
+	loadLockWait     = 50 * time.Millisecond
+	loadLockAttempts = 10
+)
+
+// Returns the cached value of key, or calls load and caches what it returns. Misses of
+// the same key are coalesced so the loader runs once no matter how many requests are
+// waiting on it. A sql.ErrNoRows from the loader is returned as is, and cached when
+// opts.NegativeTTL is set. Failing to reach the cache never fails the call, the value
+// is loaded instead.
+func GetOrLoad[T any](ctx context.Context, c Cache, kind Kind, key string, opts LoadOptions, load func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if opts.TTL == 0 {
+		opts.TTL = kind.TTL()
+	}
+
+	if entry, ok := getEntry[T](ctx, c, key); ok {
+		if entry.NotFound {
+			return zero, sql.ErrNoRows
+		}
+		if entry.stale(time.Now()) {
+			refresh(ctx, c, key, opts, load)
+		}
+		return entry.Value, nil
+	}
+
+	// The load is shared, so it shouldn't be cancelled because the request that
+	// started it went away
+	ch := loads.DoChan(key, func() (interface{}, error) {
+		return loadAndStore(context.WithoutCancel(ctx), c, key, opts, load)
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		value, _ := res.Val.(T)
+		return value, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// Reloads a stale key in the background, unless it's already being reloaded
+func refresh[T any](ctx context.Context, c Cache, key string, opts LoadOptions, load func(ctx context.Context) (T, error)) {
+	if _, busy := refreshing.LoadOrStore(key, true); busy {
+		return
+	}
+	go func() {
+		defer refreshing.Delete(key)
+		ctx := context.WithoutCancel(ctx)
+		if _, err, _ := loads.Do(key, func() (interface{}, error) {
+			return loadAndStore(ctx, c, key, opts, load)
+		}); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fmt.Printf("Error refreshing %s: %v\n", key, err)
+		}
+	}()
+}
+
+func loadAndStore[T any](ctx context.Context, c Cache, key string, opts LoadOptions, load func(ctx context.Context) (T, error)) (interface{}, error) {
+	if opts.Lock {
+		lockKey := LockKey("load:" + key)
+		token, ok, err := c.AcquireLockContext(ctx, lockKey, loadLockTTL)
+		switch {
+		case err != nil:
+			fmt.Printf("Error acquiring load lock for %s: %v\n", key, err)
+		case ok:
+			defer func() {
+				if err := c.ReleaseLockContext(ctx, lockKey, token); err != nil {
+					fmt.Printf("Error releasing load lock for %s: %v\n", key, err)
+				}
+			}()
+		default:
+			// Someone else is loading it, give them a moment to cache it
+			for i := 0; i < loadLockAttempts; i++ {
+				time.Sleep(loadLockWait)
+				if entry, ok := getEntry[T](ctx, c, key); ok {
+					if entry.NotFound {
+						return nil, sql.ErrNoRows
+					}
+					return entry.Value, nil
+				}
+			}
+		}
+	}
+
+	value, err := load(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		if opts.NegativeTTL > 0 {
+			setEntry(ctx, c, key, loadedEntry[T]{NotFound: true, FreshUntil: time.Now().Add(opts.NegativeTTL)}, opts.NegativeTTL)
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	storeValue(ctx, c, key, opts, value)
+	return value, nil
+}
+
+// Caches value under key the same way GetOrLoad caches what it loads, for writes that
+// already have the new value at hand
+func Put[T any](ctx context.Context, c Cache, kind Kind, key string, opts LoadOptions, value T) {
+	if opts.TTL == 0 {
+		opts.TTL = kind.TTL()
+	}
+	storeValue(ctx, c, key, opts, value)
+}
+
+func storeValue[T any](ctx context.Context, c Cache, key string, opts LoadOptions, value T) {
+	entry := loadedEntry[T]{Value: value}
+	var expiration time.Duration
+	if opts.TTL > 0 {
+		entry.FreshUntil = time.Now().Add(opts.TTL)
+		expiration = opts.TTL + opts.StaleWhileRevalidate
+	}
+	setEntry(ctx, c, key, entry, expiration)
+}
+
+// Reads what GetOrLoad cached under key, anything unreadable counts as a miss
+func getEntry[T any](ctx context.Context, c Cache, key string) (loadedEntry[T], bool) {
+	var entry loadedEntry[T]
+	val, err := c.GetContext(ctx, key)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			fmt.Printf("Error getting %s from cache: %v\n", key, err)
+		}
+		return entry, false
+	}
+	if err := json.Unmarshal([]byte(val), &entry); err != nil {
+		fmt.Printf("Error unmarshalling %s: %v\n", key, err)
+		return entry, false
+	}
+	return entry, true
+}
+
+func setEntry[T any](ctx context.Context, c Cache, key string, entry loadedEntry[T], expiration time.Duration) {
+	if err := c.SetWithExpirationContext(ctx, key, entry, expiration); err != nil {
+		fmt.Printf("Error caching %s: %v\n", key, err)
+	}
+}
@@ -0,0 +1,308 @@
+package redis
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Same errors Redis answers with, so both caches fail the same way
+var (
+	errWrongType  = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInteger = errors.New("ERR value is not an integer or out of range")
+)
+
+// An in-process cache used when Redis is down. Keys expire like they do in Redis,
+// and once it holds size keys the least recently used one is evicted. Nothing is
+// shared with other replicas, locks included.
+type MemoryCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	// Most recently used first
+	order *list.List
+	locks localLocks
+}
+
+type memoryEntry struct {
+	key string
+	// Only one of them is set, depending on whether the key holds a hash
+	value     string
+	hash      map[string]string
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+func NewMemoryCache(size int) *MemoryCache {
+	if size <= 0 {
+		size = 1
+	}
+	return &MemoryCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		locks:   localLocks{held: make(map[string]localLock)},
+	}
+}
+
+func (m *MemoryCache) Name() string {
+	return CacheMemory
+}
+
+func (m *MemoryCache) Shared() bool {
+	return false
+}
+
+func (m *MemoryCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+// Returns the live entry for a key and marks it as recently used, expired entries
+// are dropped on the way. Callers hold m.mu.
+func (m *MemoryCache) lookup(key string) *memoryEntry {
+	el, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := el.Value.(*memoryEntry)
+	if entry.expired(time.Now()) {
+		m.remove(el)
+		return nil
+	}
+	m.order.MoveToFront(el)
+	return entry
+}
+
+// Stores an entry, replacing whatever the key held and evicting the least recently
+// used keys to make room. Callers hold m.mu.
+func (m *MemoryCache) store(entry *memoryEntry) {
+	if el, ok := m.entries[entry.key]; ok {
+		el.Value = entry
+		m.order.MoveToFront(el)
+		return
+	}
+	m.entries[entry.key] = m.order.PushFront(entry)
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *MemoryCache) remove(el *list.Element) {
+	m.order.Remove(el)
+	delete(m.entries, el.Value.(*memoryEntry).key)
+}
+
+func (m *MemoryCache) GetContext(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.lookup(key)
+	if entry == nil {
+		return "", ErrNotFound
+	}
+	if entry.hash != nil {
+		return "", errWrongType
+	}
+	return entry.value, nil
+}
+
+func (m *MemoryCache) SetContext(ctx context.Context, key string, value interface{}) error {
+	return m.SetWithExpirationContext(ctx, key, value, defaultExpiration)
+}
+
+func (m *MemoryCache) SetWithExpirationContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	serializedValue, err := serialize(value)
+	if err != nil {
+		return err
+	}
+
+	entry := &memoryEntry{key: key, value: string(serializedValue)}
+	if expiration > 0 {
+		entry.expiresAt = time.Now().Add(expiration)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(entry)
+	return nil
+}
+
+func (m *MemoryCache) ExistsContext(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lookup(key) != nil, nil
+}
+
+func (m *MemoryCache) DeleteContext(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	return nil
+}
+
+// Like INCR, a missing key starts at 0 and the expiration is left as is
+func (m *MemoryCache) IncrementContext(ctx context.Context, key string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{key: key, value: "0"}
+	} else if entry.hash != nil {
+		return 0, errWrongType
+	}
+
+	n, err := strconv.ParseInt(entry.value, 10, 64)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	n++
+	m.store(&memoryEntry{key: key, value: strconv.FormatInt(n, 10), expiresAt: entry.expiresAt})
+	return n, nil
+}
+
+// Like HSET, fields are added to the hash and its expiration is left as is
+func (m *MemoryCache) SetHashContext(ctx context.Context, key string, fields map[string]interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := m.lookup(key)
+	if entry == nil {
+		entry = &memoryEntry{key: key, hash: make(map[string]string)}
+	} else if entry.hash == nil {
+		return errWrongType
+	}
+
+	hash := make(map[string]string, len(entry.hash)+len(fields))
+	for field, value := range entry.hash {
+		hash[field] = value
+	}
+	for field, value := range fields {
+		hash[field] = formatField(value)
+	}
+	m.store(&memoryEntry{key: key, hash: hash, expiresAt: entry.expiresAt})
+	return nil
+}
+
+func (m *MemoryCache) GetHashContext(ctx context.Context, key string) (map[string]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hash := make(map[string]string)
+	entry := m.lookup(key)
+	if entry == nil {
+		return hash, nil
+	}
+	if entry.hash == nil {
+		return nil, errWrongType
+	}
+	for field, value := range entry.hash {
+		hash[field] = value
+	}
+	return hash, nil
+}
+
+// Hash fields are stored the way go-redis sends them to Redis
+func formatField(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Evicts the matching keys, nothing else shares this cache so there's nobody to tell
+func (m *MemoryCache) InvalidateContext(ctx context.Context, inv Invalidation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, el := range m.entries {
+		if inv.matches(key) {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+// Evicts every key
+func (m *MemoryCache) flush() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.entries = make(map[string]*list.Element)
+	m.order.Init()
+}
+
+func (m *MemoryCache) AcquireLockContext(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	return m.locks.acquire(key, ttl)
+}
+
+func (m *MemoryCache) ReleaseLockContext(ctx context.Context, key, token string) error {
+	m.locks.release(key, token)
+	return nil
+}
+
+// Locks that only cover this process, kept apart from the cached keys so they're
+// never evicted
+type localLocks struct {
+	mu   sync.Mutex
+	held map[string]localLock
+}
+
+type localLock struct {
+	token     string
+	expiresAt time.Time
+}
+
+func (l *localLocks) acquire(key string, ttl time.Duration) (string, bool, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if lock, ok := l.held[key]; ok && (lock.expiresAt.IsZero() || now.Before(lock.expiresAt)) {
+		return "", false, nil
+	}
+
+	lock := localLock{token: hex.EncodeToString(b)}
+	if ttl > 0 {
+		lock.expiresAt = now.Add(ttl)
+	}
+	l.held[key] = lock
+	return lock.token, true, nil
+}
+
+func (l *localLocks) release(key, token string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if lock, ok := l.held[key]; ok && lock.token == token {
+		delete(l.held, key)
+	}
+}
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Returned by NoopCache for operations that need the value back, like INCR
+var ErrCacheDisabled = errors.New("cache disabled")
+
+// Cache-less mode: nothing is stored, every read is a miss. Locks still work
+// within the process so a replica doesn't run the same job twice.
+type NoopCache struct {
+	locks *localLocks
+}
+
+func NewNoopCache() NoopCache {
+	return NoopCache{locks: &localLocks{held: make(map[string]localLock)}}
+}
+
+func (NoopCache) Name() string {
+	return CacheNone
+}
+
+func (NoopCache) Shared() bool {
+	return false
+}
+
+func (NoopCache) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (NoopCache) GetContext(ctx context.Context, key string) (string, error) {
+	return "", ErrNotFound
+}
+
+func (NoopCache) SetContext(ctx context.Context, key string, value interface{}) error {
+	return nil
+}
+
+func (NoopCache) SetWithExpirationContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return nil
+}
+
+func (NoopCache) ExistsContext(ctx context.Context, key string) (bool, error) {
+	return false, nil
+}
+
+func (NoopCache) DeleteContext(ctx context.Context, key string) error {
+	return nil
+}
+
+func (NoopCache) IncrementContext(ctx context.Context, key string) (int64, error) {
+	return 0, ErrCacheDisabled
+}
+
+func (NoopCache) SetHashContext(ctx context.Context, key string, fields map[string]interface{}) error {
+	return nil
+}
+
+func (NoopCache) GetHashContext(ctx context.Context, key string) (map[string]string, error) {
+	return map[string]string{}, nil
+}
+
+func (NoopCache) InvalidateContext(ctx context.Context, inv Invalidation) error {
+	return nil
+}
+
+func (n NoopCache) AcquireLockContext(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	return n.locks.acquire(key, ttl)
+}
+
+func (n NoopCache) ReleaseLockContext(ctx context.Context, key, token string) error {
+	n.locks.release(key, token)
+	return nil
+}
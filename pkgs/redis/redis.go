@@ -2,88 +2,219 @@ package redis
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
-	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 type RedisClient struct {
-	client *redis.Client
+	client redis.UniversalClient
+	// Namespace put in front of every key
+	prefix string
 }
 
-// Initialize Redis client as singleton to prevent multiple
-// connections and enable usage via imports
-var (
-	instance *RedisClient
-	once     sync.Once
-)
+// Where Redis is and how to talk to it
+type Options struct {
+	// redis://[user:password@]host:port/db, rediss:// for TLS or unix:///path/to/redis.sock,
+	// see redis.ParseURL for the query parameters it also takes
+	URL string
+
+	// Sentinel setups: the master's name and the sentinels' host:port addresses. The
+	// URL's credentials and DB are used for the master.
+	SentinelMaster   string
+	SentinelAddrs    []string
+	SentinelPassword string
+
+	// Cluster setups: host:port addresses of some of the nodes, the rest are
+	// discovered. The URL's credentials and TLS settings are used for every node.
+	ClusterAddrs []string
+
+	// 0 keeps the go-redis defaults
+	PoolSize     int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+
+	// Put in front of every key, e.g. "staging:", so deployments can share a Redis
+	KeyPrefix string
+}
 
 // Default settings for redis
 var (
-	REDIS_ADDR     = "localhost:6379"
-	REDIS_PASSWORD = ""
+	REDIS_OPTIONS = Options{URL: "redis://localhost:6379/0"}
+	// Deadline for each operation, 0 means no deadline
+	REDIS_TIMEOUT = 2 * time.Second
 )
 
-func GetInstance() *RedisClient {
-	once.Do(func() {
-		rdb := redis.NewClient(&redis.Options{
-			Addr:     REDIS_ADDR,
-			Password: REDIS_PASSWORD,
-			DB:       0,
-		})
-
-		// Ping to check connection
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		_, err := rdb.Ping(ctx).Result()
-		if err != nil {
-			log.Fatalf("Failed to connect to Redis: %v", err)
+// Creates the client the options describe: a cluster client when there are cluster
+// nodes, a failover client when there's a sentinel master and a plain one otherwise
+func newClient(opts Options) (redis.UniversalClient, error) {
+	parsed, err := redis.ParseURL(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %v", err)
+	}
+
+	universal := &redis.UniversalOptions{
+		Addrs:            []string{parsed.Addr},
+		DB:               parsed.DB,
+		Username:         parsed.Username,
+		Password:         parsed.Password,
+		SentinelPassword: opts.SentinelPassword,
+		MaxRetries:       parsed.MaxRetries,
+		DialTimeout:      parsed.DialTimeout,
+		ReadTimeout:      parsed.ReadTimeout,
+		WriteTimeout:     parsed.WriteTimeout,
+		PoolSize:         parsed.PoolSize,
+		TLSConfig:        parsed.TLSConfig,
+	}
+	if opts.PoolSize > 0 {
+		universal.PoolSize = opts.PoolSize
+	}
+	if opts.DialTimeout > 0 {
+		universal.DialTimeout = opts.DialTimeout
+	}
+	if opts.ReadTimeout > 0 {
+		universal.ReadTimeout = opts.ReadTimeout
+	}
+	if opts.WriteTimeout > 0 {
+		universal.WriteTimeout = opts.WriteTimeout
+	}
+
+	switch {
+	case len(opts.ClusterAddrs) > 0:
+		if parsed.DB != 0 {
+			return nil, fmt.Errorf("redis cluster only has DB 0, got %d", parsed.DB)
+		}
+		universal.Addrs = opts.ClusterAddrs
+		return redis.NewClusterClient(universal.Cluster()), nil
+	case opts.SentinelMaster != "":
+		if len(opts.SentinelAddrs) == 0 {
+			return nil, fmt.Errorf("sentinel master %s needs sentinel addresses", opts.SentinelMaster)
 		}
+		universal.Addrs = opts.SentinelAddrs
+		universal.MasterName = opts.SentinelMaster
+		return redis.NewFailoverClient(universal.Failover()), nil
+	default:
+		return redis.NewClient(universal.Simple()), nil
+	}
+}
+
+// A client for the Redis the options describe, which connects once it's used
+func NewRedisClient(opts Options) (*RedisClient, error) {
+	rdb, err := newClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisClient{client: rdb, prefix: opts.KeyPrefix}, nil
+}
 
-		instance = &RedisClient{client: rdb}
-	})
+// Connects to Redis and pings it, so a Redis that's down is noticed right away
+func Connect(ctx context.Context, opts Options) (*RedisClient, error) {
+	r, err := NewRedisClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ping to check connection
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := r.client.Ping(ctx).Result(); err != nil {
+		r.client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	}
 
-	return instance
+	return r, nil
 }
 
-func GenerateTupleKey(key1, key2 string) string {
-	return fmt.Sprintf("(%s, %s)", key1, key2)
+// Namespaces a key with the configured prefix
+func (r *RedisClient) key(key string) string {
+	return r.prefix + key
+}
+
+func (r *RedisClient) Name() string {
+	return CacheRedis
+}
+
+func (r *RedisClient) Shared() bool {
+	return true
+}
+
+func (r *RedisClient) Ping(ctx context.Context) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return r.client.Ping(ctx).Err()
+}
+
+// Applies REDIS_TIMEOUT to an operation's context
+func withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if REDIS_TIMEOUT <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, REDIS_TIMEOUT)
 }
 
-// Set a key-value pair with optional expiration
+// Values are stored as JSON, unless they're already raw bytes
+func serialize(value interface{}) ([]byte, error) {
+	if v, ok := value.([]byte); ok {
+		return v, nil
+	}
+	serializedValue, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize value: %v", err)
+	}
+	return serializedValue, nil
+}
+
+// Set a key-value pair with the default expiration
 func (r *RedisClient) Set(key string, value interface{}) error {
-	defaultExpiration := 2 * time.Minute
+	return r.SetContext(context.Background(), key, value)
+}
 
-	// Serialize the value to JSON
-	var serializedValue []byte
-	var err error
+func (r *RedisClient) SetContext(ctx context.Context, key string, value interface{}) error {
+	return r.SetWithExpirationContext(ctx, key, value, defaultExpiration)
+}
 
-	switch v := value.(type) {
-	case []byte:
-		serializedValue = v
-	default:
-		serializedValue, err = json.Marshal(value)
-		if err != nil {
-			return fmt.Errorf("failed to serialize value: %v", err)
-		}
+// Set a key-value pair with a custom expiration, 0 means the key never expires
+func (r *RedisClient) SetWithExpiration(key string, value interface{}, expiration time.Duration) error {
+	return r.SetWithExpirationContext(context.Background(), key, value, expiration)
+}
+
+func (r *RedisClient) SetWithExpirationContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	serializedValue, err := serialize(value)
+	if err != nil {
+		return err
 	}
 
-	return r.client.Set(context.Background(), key, serializedValue, defaultExpiration).Err()
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return r.client.Set(ctx, r.key(key), serializedValue, expiration).Err()
 }
 
 // Retrieve a value for a given key
 func (r *RedisClient) Get(key string) (string, error) {
-	return r.client.Get(context.Background(), key).Result()
+	return r.GetContext(context.Background(), key)
+}
+
+func (r *RedisClient) GetContext(ctx context.Context, key string) (string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return r.client.Get(ctx, r.key(key)).Result()
 }
 
 // Check if key exists in the cache
 func (r *RedisClient) Exists(key string) (bool, error) {
-	count, err := r.client.Exists(context.Background(), key).Result()
+	return r.ExistsContext(context.Background(), key)
+}
+
+func (r *RedisClient) ExistsContext(ctx context.Context, key string) (bool, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	count, err := r.client.Exists(ctx, r.key(key)).Result()
 	if err != nil {
 		return false, err
 	}
@@ -92,12 +223,24 @@ func (r *RedisClient) Exists(key string) (bool, error) {
 
 // Remove a key
 func (r *RedisClient) Delete(key string) error {
-	return r.client.Del(context.Background(), key).Err()
+	return r.DeleteContext(context.Background(), key)
+}
+
+func (r *RedisClient) DeleteContext(ctx context.Context, key string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return r.client.Del(ctx, r.key(key)).Err()
 }
 
 // Increments the integer value of a key
 func (r *RedisClient) Increment(key string) (int64, error) {
-	return r.client.Incr(context.Background(), key).Result()
+	return r.IncrementContext(context.Background(), key)
+}
+
+func (r *RedisClient) IncrementContext(ctx context.Context, key string) (int64, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return r.client.Incr(ctx, r.key(key)).Result()
 }
 
 // The below hash operations let us store KV pairs (specifically key, string pairs), which can be
@@ -105,10 +248,68 @@ func (r *RedisClient) Increment(key string) (int64, error) {
 
 // Set multiple fields in a hash
 func (r *RedisClient) SetHash(key string, fields map[string]interface{}) error {
-	return r.client.HMSet(context.Background(), key, fields).Err()
+	return r.SetHashContext(context.Background(), key, fields)
+}
+
+func (r *RedisClient) SetHashContext(ctx context.Context, key string, fields map[string]interface{}) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return r.client.HMSet(ctx, r.key(key), fields).Err()
 }
 
 // Retrieves all fields of a hash
 func (r *RedisClient) GetHash(key string) (map[string]string, error) {
-	return r.client.HGetAll(context.Background(), key).Result()
+	return r.GetHashContext(context.Background(), key)
+}
+
+func (r *RedisClient) GetHashContext(ctx context.Context, key string) (map[string]string, error) {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return r.client.HGetAll(ctx, r.key(key)).Result()
+}
+
+// The below lock operations implement a simple distributed lock (SET NX with an expiry)
+// so that only one replica does a given job at a time.
+// https://redis.io/docs/latest/develop/use/patterns/distributed-locks/#correct-implementation-with-a-single-instance
+
+// Only deletes the lock if it's still held by whoever has the token, so an expired
+// lock that someone else acquired since then isn't released by mistake
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Tries to acquire the lock, returns the token needed to release it and whether it
+// was acquired. The lock expires on its own after ttl in case the holder dies.
+func (r *RedisClient) AcquireLock(key string, ttl time.Duration) (string, bool, error) {
+	return r.AcquireLockContext(context.Background(), key, ttl)
+}
+
+func (r *RedisClient) AcquireLockContext(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, err
+	}
+	token := hex.EncodeToString(b)
+
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	ok, err := r.client.SetNX(ctx, r.key(key), token, ttl).Result()
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return token, true, nil
+}
+
+// Releases a lock acquired with AcquireLock
+func (r *RedisClient) ReleaseLock(key, token string) error {
+	return r.ReleaseLockContext(context.Background(), key, token)
+}
+
+func (r *RedisClient) ReleaseLockContext(ctx context.Context, key, token string) error {
+	ctx, cancel := withTimeout(ctx)
+	defer cancel()
+	return releaseLockScript.Run(ctx, r.client, []string{r.key(key)}, token).Err()
 }
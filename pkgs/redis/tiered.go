@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// An in-process cache (L1) in front of Redis, so hot keys don't need a round trip.
+// Writes go to both, and every invalidation is published so the other replicas evict
+// the keys from their own L1. Entries live in L1 for at most l1TTL, which bounds how
+// stale a replica that missed an invalidation can get.
+type TieredCache struct {
+	l1    *MemoryCache
+	l2    *RedisClient
+	l1TTL time.Duration
+	// Tells our own invalidations apart from the other replicas'
+	id string
+}
+
+// Puts an in-process cache of size keys in front of r, and listens for the other
+// replicas' invalidations until ctx is done
+func NewTieredCache(ctx context.Context, r *RedisClient, size int, l1TTL time.Duration) *TieredCache {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	t := &TieredCache{
+		l1:    NewMemoryCache(size),
+		l2:    r,
+		l1TTL: l1TTL,
+		id:    hex.EncodeToString(b),
+	}
+	go r.subscribeInvalidations(ctx, t.id, func(inv Invalidation) {
+		t.l1.InvalidateContext(ctx, inv)
+	}, t.l1.flush)
+	return t
+}
+
+func (t *TieredCache) Name() string {
+	return CacheTiered
+}
+
+func (t *TieredCache) Shared() bool {
+	return true
+}
+
+func (t *TieredCache) Ping(ctx context.Context) error {
+	return t.l2.Ping(ctx)
+}
+
+// How long a value written with the given expiration stays in L1
+func (t *TieredCache) l1Expiration(expiration time.Duration) time.Duration {
+	if expiration <= 0 || expiration > t.l1TTL {
+		return t.l1TTL
+	}
+	return expiration
+}
+
+func (t *TieredCache) GetContext(ctx context.Context, key string) (string, error) {
+	if val, err := t.l1.GetContext(ctx, key); err == nil {
+		return val, nil
+	}
+	val, err := t.l2.GetContext(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	t.l1.SetWithExpirationContext(ctx, key, []byte(val), t.l1TTL)
+	return val, nil
+}
+
+func (t *TieredCache) SetContext(ctx context.Context, key string, value interface{}) error {
+	return t.SetWithExpirationContext(ctx, key, value, defaultExpiration)
+}
+
+func (t *TieredCache) SetWithExpirationContext(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	serializedValue, err := serialize(value)
+	if err != nil {
+		return err
+	}
+	if err := t.l2.SetWithExpirationContext(ctx, key, serializedValue, expiration); err != nil {
+		return err
+	}
+	return t.l1.SetWithExpirationContext(ctx, key, serializedValue, t.l1Expiration(expiration))
+}
+
+func (t *TieredCache) ExistsContext(ctx context.Context, key string) (bool, error) {
+	if ok, _ := t.l1.ExistsContext(ctx, key); ok {
+		return true, nil
+	}
+	return t.l2.ExistsContext(ctx, key)
+}
+
+// Deletes the key everywhere, the other replicas' L1 included
+func (t *TieredCache) DeleteContext(ctx context.Context, key string) error {
+	return t.InvalidateContext(ctx, Invalidation{Keys: []string{key}})
+}
+
+// Counters and hashes only live in Redis, so every replica sees the same ones
+func (t *TieredCache) IncrementContext(ctx context.Context, key string) (int64, error) {
+	return t.l2.IncrementContext(ctx, key)
+}
+
+func (t *TieredCache) SetHashContext(ctx context.Context, key string, fields map[string]interface{}) error {
+	return t.l2.SetHashContext(ctx, key, fields)
+}
+
+func (t *TieredCache) GetHashContext(ctx context.Context, key string) (map[string]string, error) {
+	return t.l2.GetHashContext(ctx, key)
+}
+
+func (t *TieredCache) InvalidateContext(ctx context.Context, inv Invalidation) error {
+	t.l1.InvalidateContext(ctx, inv)
+	return t.l2.invalidate(ctx, t.id, inv)
+}
+
+func (t *TieredCache) AcquireLockContext(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	return t.l2.AcquireLockContext(ctx, key, ttl)
+}
+
+func (t *TieredCache) ReleaseLockContext(ctx context.Context, key, token string) error {
+	return t.l2.ReleaseLockContext(ctx, key, token)
+}
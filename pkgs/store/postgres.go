@@ -0,0 +1,359 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/johncmanuel/cpsc449-project2/db/psql"
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+)
+
+// Runs the queries generated from db/psql/queries.sql. Both packages are generated
+// from the same schema with the same Go types (see db/sqlc.yaml), so their models
+// convert into each other directly.
+type postgresStore struct {
+	q    *psql.Queries
+	pool *pgxpool.Pool // nil inside a transaction
+}
+
+// pgx has its own error for a missing row, callers only know about sql.ErrNoRows
+func noRows(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+	return err
+}
+
+// Postgres takes an int4 for LIMIT
+func limit32(limit int64) int32 {
+	return int32(min(max(limit, 0), math.MaxInt32))
+}
+
+func convertAll[From, To any](items []From, convert func(From) To) []To {
+	if items == nil {
+		return nil
+	}
+	converted := make([]To, len(items))
+	for i, item := range items {
+		converted[i] = convert(item)
+	}
+	return converted
+}
+
+func toAssignment(a psql.Assignment) sqlite.Assignment {
+	return sqlite.Assignment(a)
+}
+
+func toCourse(c psql.Course) sqlite.Course {
+	return sqlite.Course(c)
+}
+
+func toSyncRun(r psql.SyncRun) sqlite.SyncRun {
+	return sqlite.SyncRun(r)
+}
+
+func (s *postgresStore) CountAssignments(ctx context.Context, arg sqlite.CountAssignmentsParams) (int64, error) {
+	return s.q.CountAssignments(ctx, psql.CountAssignmentsParams(arg))
+}
+
+func (s *postgresStore) CountCourseAssignments(ctx context.Context, arg sqlite.CountCourseAssignmentsParams) (int64, error) {
+	return s.q.CountCourseAssignments(ctx, psql.CountCourseAssignmentsParams(arg))
+}
+
+func (s *postgresStore) CreateAssignmentEvent(ctx context.Context, arg sqlite.CreateAssignmentEventParams) error {
+	return s.q.CreateAssignmentEvent(ctx, psql.CreateAssignmentEventParams(arg))
+}
+
+func (s *postgresStore) CreateSyncRun(ctx context.Context, arg sqlite.CreateSyncRunParams) (sqlite.SyncRun, error) {
+	run, err := s.q.CreateSyncRun(ctx, psql.CreateSyncRunParams(arg))
+	return toSyncRun(run), err
+}
+
+func (s *postgresStore) CreateTask(ctx context.Context, arg sqlite.CreateTaskParams) (sqlite.Assignment, error) {
+	assignment, err := s.q.CreateTask(ctx, psql.CreateTaskParams(arg))
+	return toAssignment(assignment), err
+}
+
+func (s *postgresStore) DeleteAssignment(ctx context.Context, arg sqlite.DeleteAssignmentParams) error {
+	return s.q.DeleteAssignment(ctx, psql.DeleteAssignmentParams(arg))
+}
+
+func (s *postgresStore) DeleteAssignmentOverride(ctx context.Context, assignmentID int64) error {
+	return s.q.DeleteAssignmentOverride(ctx, assignmentID)
+}
+
+func (s *postgresStore) DeleteAssignmentsByCourse(ctx context.Context, courseID int64) error {
+	return s.q.DeleteAssignmentsByCourse(ctx, courseID)
+}
+
+func (s *postgresStore) DeleteCourse(ctx context.Context, id int64) error {
+	return s.q.DeleteCourse(ctx, id)
+}
+
+func (s *postgresStore) DeleteTask(ctx context.Context, id int64) (int64, error) {
+	return s.q.DeleteTask(ctx, id)
+}
+
+func (s *postgresStore) FinishSyncRun(ctx context.Context, arg sqlite.FinishSyncRunParams) error {
+	return s.q.FinishSyncRun(ctx, psql.FinishSyncRunParams(arg))
+}
+
+func (s *postgresStore) GetAssignment(ctx context.Context, arg sqlite.GetAssignmentParams) (sqlite.Assignment, error) {
+	assignment, err := s.q.GetAssignment(ctx, psql.GetAssignmentParams(arg))
+	return toAssignment(assignment), noRows(err)
+}
+
+func (s *postgresStore) GetAssignmentCountsByCourse(ctx context.Context) ([]sqlite.GetAssignmentCountsByCourseRow, error) {
+	rows, err := s.q.GetAssignmentCountsByCourse(ctx)
+	return convertAll(rows, func(r psql.GetAssignmentCountsByCourseRow) sqlite.GetAssignmentCountsByCourseRow {
+		return sqlite.GetAssignmentCountsByCourseRow(r)
+	}), err
+}
+
+func (s *postgresStore) GetAssignmentOverride(ctx context.Context, assignmentID int64) (sqlite.AssignmentOverride, error) {
+	override, err := s.q.GetAssignmentOverride(ctx, assignmentID)
+	return sqlite.AssignmentOverride(override), noRows(err)
+}
+
+func (s *postgresStore) GetCourse(ctx context.Context, id int64) (sqlite.Course, error) {
+	course, err := s.q.GetCourse(ctx, id)
+	return toCourse(course), noRows(err)
+}
+
+func (s *postgresStore) GetCourseSyncState(ctx context.Context, courseID int64) (sqlite.CourseSyncState, error) {
+	state, err := s.q.GetCourseSyncState(ctx, courseID)
+	return sqlite.CourseSyncState(state), noRows(err)
+}
+
+func (s *postgresStore) GetTask(ctx context.Context, id int64) (sqlite.Assignment, error) {
+	assignment, err := s.q.GetTask(ctx, id)
+	return toAssignment(assignment), noRows(err)
+}
+
+func (s *postgresStore) ListAllAssignments(ctx context.Context) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListAllAssignments(ctx)
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListAllCourses(ctx context.Context) ([]sqlite.Course, error) {
+	courses, err := s.q.ListAllCourses(ctx)
+	return convertAll(courses, toCourse), err
+}
+
+func (s *postgresStore) ListAssignmentEventsSince(ctx context.Context, createdAt time.Time) ([]sqlite.AssignmentEvent, error) {
+	events, err := s.q.ListAssignmentEventsSince(ctx, createdAt)
+	return convertAll(events, func(e psql.AssignmentEvent) sqlite.AssignmentEvent {
+		return sqlite.AssignmentEvent(e)
+	}), err
+}
+
+func (s *postgresStore) ListAssignmentOverridesByCourse(ctx context.Context, courseID int64) ([]sqlite.AssignmentOverride, error) {
+	overrides, err := s.q.ListAssignmentOverridesByCourse(ctx, courseID)
+	return convertAll(overrides, func(o psql.AssignmentOverride) sqlite.AssignmentOverride {
+		return sqlite.AssignmentOverride(o)
+	}), err
+}
+
+func (s *postgresStore) ListAssignmentsByCourse(ctx context.Context, courseID int64) ([]sqlite.ListAssignmentsByCourseRow, error) {
+	rows, err := s.q.ListAssignmentsByCourse(ctx, courseID)
+	return convertAll(rows, func(r psql.ListAssignmentsByCourseRow) sqlite.ListAssignmentsByCourseRow {
+		return sqlite.ListAssignmentsByCourseRow(r)
+	}), err
+}
+
+func (s *postgresStore) ListAssignmentsByDifficulty(ctx context.Context, arg sqlite.ListAssignmentsByDifficultyParams) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListAssignmentsByDifficulty(ctx, psql.ListAssignmentsByDifficultyParams{
+		IncludeSubmitted: arg.IncludeSubmitted,
+		CourseID:         arg.CourseID,
+		DueAfter:         arg.DueAfter,
+		DueBefore:        arg.DueBefore,
+		Overdue:          arg.Overdue,
+		Now:              arg.Now,
+		MinDifficulty:    arg.MinDifficulty,
+		HasDueDate:       arg.HasDueDate,
+		AfterID:          arg.AfterID,
+		AfterDifficulty:  arg.AfterDifficulty,
+		Limit:            limit32(arg.Limit),
+	})
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListAssignmentsByDueDate(ctx context.Context, arg sqlite.ListAssignmentsByDueDateParams) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListAssignmentsByDueDate(ctx, psql.ListAssignmentsByDueDateParams{
+		IncludeSubmitted: arg.IncludeSubmitted,
+		CourseID:         arg.CourseID,
+		DueAfter:         arg.DueAfter,
+		DueBefore:        arg.DueBefore,
+		Overdue:          arg.Overdue,
+		Now:              arg.Now,
+		MinDifficulty:    arg.MinDifficulty,
+		HasDueDate:       arg.HasDueDate,
+		AfterID:          arg.AfterID,
+		AfterDueDate:     arg.AfterDueDate,
+		Limit:            limit32(arg.Limit),
+	})
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListAssignmentsByName(ctx context.Context, arg sqlite.ListAssignmentsByNameParams) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListAssignmentsByName(ctx, psql.ListAssignmentsByNameParams{
+		IncludeSubmitted: arg.IncludeSubmitted,
+		CourseID:         arg.CourseID,
+		DueAfter:         arg.DueAfter,
+		DueBefore:        arg.DueBefore,
+		Overdue:          arg.Overdue,
+		Now:              arg.Now,
+		MinDifficulty:    arg.MinDifficulty,
+		HasDueDate:       arg.HasDueDate,
+		AfterID:          arg.AfterID,
+		AfterName:        arg.AfterName,
+		Limit:            limit32(arg.Limit),
+	})
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListAssignmentsWithoutDescriptionText(ctx context.Context) ([]sqlite.ListAssignmentsWithoutDescriptionTextRow, error) {
+	rows, err := s.q.ListAssignmentsWithoutDescriptionText(ctx)
+	return convertAll(rows, func(r psql.ListAssignmentsWithoutDescriptionTextRow) sqlite.ListAssignmentsWithoutDescriptionTextRow {
+		return sqlite.ListAssignmentsWithoutDescriptionTextRow(r)
+	}), err
+}
+
+func (s *postgresStore) ListCourseAssignments(ctx context.Context, courseID int64) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListCourseAssignments(ctx, courseID)
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListCourseAssignmentsByDueDate(ctx context.Context, arg sqlite.ListCourseAssignmentsByDueDateParams) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListCourseAssignmentsByDueDate(ctx, psql.ListCourseAssignmentsByDueDateParams{
+		IncludeSubmitted: arg.IncludeSubmitted,
+		CourseID:         arg.CourseID,
+		DueAfter:         arg.DueAfter,
+		DueBefore:        arg.DueBefore,
+		Overdue:          arg.Overdue,
+		Now:              arg.Now,
+		MinDifficulty:    arg.MinDifficulty,
+		HasDueDate:       arg.HasDueDate,
+		AfterID:          arg.AfterID,
+		AfterDueDate:     arg.AfterDueDate,
+		Limit:            limit32(arg.Limit),
+	})
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListPendingAssignments(ctx context.Context) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListPendingAssignments(ctx)
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListSyncRuns(ctx context.Context, limit int64) ([]sqlite.SyncRun, error) {
+	runs, err := s.q.ListSyncRuns(ctx, limit32(limit))
+	return convertAll(runs, toSyncRun), err
+}
+
+func (s *postgresStore) ListTasks(ctx context.Context) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListTasks(ctx)
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListUpcomingAssignments(ctx context.Context, arg sqlite.ListUpcomingAssignmentsParams) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListUpcomingAssignments(ctx, psql.ListUpcomingAssignmentsParams{
+		DueDate: arg.DueDate,
+		Limit:   limit32(arg.Limit),
+	})
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) ListUpcomingPendingAssignments(ctx context.Context, arg sqlite.ListUpcomingPendingAssignmentsParams) ([]sqlite.Assignment, error) {
+	assignments, err := s.q.ListUpcomingPendingAssignments(ctx, psql.ListUpcomingPendingAssignmentsParams{
+		DueDate: arg.DueDate,
+		Limit:   limit32(arg.Limit),
+	})
+	return convertAll(assignments, toAssignment), err
+}
+
+func (s *postgresStore) Search(ctx context.Context, arg sqlite.SearchParams) ([]sqlite.SearchRow, error) {
+	// SQLite takes the courses as a JSON array, pgx wants a slice
+	var courseIDs []int64
+	if arg.CourseIDs.Valid {
+		if err := json.Unmarshal([]byte(arg.CourseIDs.String), &courseIDs); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := s.q.Search(ctx, psql.SearchParams{
+		Match:     tsquery(arg.Match),
+		CourseIDs: courseIDs,
+		Limit:     limit32(arg.Limit),
+	})
+	return convertAll(rows, func(r psql.SearchRow) sqlite.SearchRow {
+		return sqlite.SearchRow(r)
+	}), err
+}
+
+func (s *postgresStore) UpdateAssignment(ctx context.Context, arg sqlite.UpdateAssignmentParams) error {
+	return s.q.UpdateAssignment(ctx, psql.UpdateAssignmentParams(arg))
+}
+
+func (s *postgresStore) UpdateAssignmentDescriptionText(ctx context.Context, arg sqlite.UpdateAssignmentDescriptionTextParams) error {
+	return s.q.UpdateAssignmentDescriptionText(ctx, psql.UpdateAssignmentDescriptionTextParams(arg))
+}
+
+func (s *postgresStore) UpdateAssignmentIfVersion(ctx context.Context, arg sqlite.UpdateAssignmentIfVersionParams) (sqlite.Assignment, error) {
+	assignment, err := s.q.UpdateAssignmentIfVersion(ctx, psql.UpdateAssignmentIfVersionParams(arg))
+	return toAssignment(assignment), noRows(err)
+}
+
+func (s *postgresStore) UpdateAssignmentOverrideCanvas(ctx context.Context, arg sqlite.UpdateAssignmentOverrideCanvasParams) error {
+	return s.q.UpdateAssignmentOverrideCanvas(ctx, psql.UpdateAssignmentOverrideCanvasParams(arg))
+}
+
+func (s *postgresStore) UpdateTaskIfVersion(ctx context.Context, arg sqlite.UpdateTaskIfVersionParams) (sqlite.Assignment, error) {
+	assignment, err := s.q.UpdateTaskIfVersion(ctx, psql.UpdateTaskIfVersionParams(arg))
+	return toAssignment(assignment), noRows(err)
+}
+
+func (s *postgresStore) UpsertAssignment(ctx context.Context, arg sqlite.UpsertAssignmentParams) (sqlite.Assignment, error) {
+	assignment, err := s.q.UpsertAssignment(ctx, psql.UpsertAssignmentParams(arg))
+	return toAssignment(assignment), err
+}
+
+func (s *postgresStore) UpsertAssignmentOverride(ctx context.Context, arg sqlite.UpsertAssignmentOverrideParams) error {
+	return s.q.UpsertAssignmentOverride(ctx, psql.UpsertAssignmentOverrideParams(arg))
+}
+
+func (s *postgresStore) UpsertCourse(ctx context.Context, arg sqlite.UpsertCourseParams) (sqlite.Course, error) {
+	course, err := s.q.UpsertCourse(ctx, psql.UpsertCourseParams(arg))
+	return toCourse(course), err
+}
+
+func (s *postgresStore) UpsertCourseSyncState(ctx context.Context, arg sqlite.UpsertCourseSyncStateParams) error {
+	return s.q.UpsertCourseSyncState(ctx, psql.UpsertCourseSyncStateParams(arg))
+}
+
+func (s *postgresStore) UpsertSubmission(ctx context.Context, arg sqlite.UpsertSubmissionParams) error {
+	return s.q.UpsertSubmission(ctx, psql.UpsertSubmissionParams(arg))
+}
+
+func (s *postgresStore) UpsertSyllabus(ctx context.Context, arg sqlite.UpsertSyllabusParams) (sqlite.Syllabus, error) {
+	syllabus, err := s.q.UpsertSyllabus(ctx, psql.UpsertSyllabusParams(arg))
+	return sqlite.Syllabus(syllabus), err
+}
+
+// Transactions are repeatable read, so like SQLite's, writing a row another transaction
+// changed after this one started fails instead of overwriting the change (the sync
+// counts on it to never overwrite an override saved while it runs)
+func (s *postgresStore) WithTx(ctx context.Context, fn func(Store) error) error {
+	if s.pool == nil {
+		return fn(s)
+	}
+	return pgx.BeginTxFunc(ctx, s.pool, pgx.TxOptions{IsoLevel: pgx.RepeatableRead}, func(tx pgx.Tx) error {
+		return fn(&postgresStore{q: s.q.WithTx(tx)})
+	})
+}
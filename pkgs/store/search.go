@@ -0,0 +1,120 @@
+package store
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// What Search puts around the words that matched in a snippet, see queries.sql
+const (
+	snippetStart = "\x02"
+	snippetStop  = "\x03"
+)
+
+// Turns what the user typed into the FTS5 query Search takes: every word has to match,
+// and the last one can be the start of a word since it may still be being typed. The
+// words are quoted so FTS5 never reads them as operators (AND, NEAR, column filters).
+// Returns false when there's no word to search for.
+func MatchQuery(text string) (string, bool) {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return "", false
+	}
+	for i, word := range words {
+		words[i] = `"` + word + `"`
+	}
+	words[len(words)-1] += "*"
+	return strings.Join(words, " "), true
+}
+
+// Translates a query made by MatchQuery for Postgres' to_tsquery, e.g. "lab" "3"* is
+// 'lab' & '3':*
+func tsquery(match string) string {
+	terms := strings.Fields(match)
+	for i, term := range terms {
+		prefix := strings.HasSuffix(term, "*")
+		term = strings.Trim(term, `"*`)
+		term = "'" + strings.ReplaceAll(term, "'", "''") + "'"
+		if prefix {
+			term += ":*"
+		}
+		terms[i] = term
+	}
+	return strings.Join(terms, " & ")
+}
+
+// Number of words in a snippet, as many as the FTS5 snippets have
+const snippetWords = 16
+
+// The words of a query made by MatchQuery, without their quotes and *
+func matchWords(match string) []string {
+	words := strings.Fields(match)
+	for i, word := range words {
+		words[i] = strings.Trim(word, `"*`)
+	}
+	return words
+}
+
+// The LIKE pattern SearchLike takes, matching text with the words in that order. The
+// words are only letters and numbers (see MatchQuery), so none of them need escaping.
+func likePattern(words []string) string {
+	return "%" + strings.Join(words, "%") + "%"
+}
+
+// Cuts a snippet out of text the way the FTS5 ones are: around the first word that
+// matched, with every match marked. Returns false when no word of text matched, the
+// snippet is then the start of text.
+func likeSnippet(text string, words []string) (string, bool) {
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r)
+	}
+	fields := strings.Fields(text)
+	first := -1
+	for i, field := range fields {
+		// Leave out the punctuation around the word, "(report)," marks report
+		core := strings.TrimLeftFunc(field, func(r rune) bool { return !isWord(r) })
+		lead := field[:len(field)-len(core)]
+		core = strings.TrimRightFunc(core, func(r rune) bool { return !isWord(r) })
+		trail := field[len(lead)+len(core):]
+		if core == "" || !containsAny(core, words) {
+			continue
+		}
+		fields[i] = lead + snippetStart + core + snippetStop + trail
+		if first < 0 {
+			first = i
+		}
+	}
+
+	start := max(min(first-snippetWords/4, len(fields)-snippetWords), 0)
+	end := min(start+snippetWords, len(fields))
+	snippet := strings.Join(fields[start:end], " ")
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(fields) {
+		snippet += "..."
+	}
+	return snippet, first >= 0
+}
+
+// Whether s contains any of the words, ignoring case like LIKE does
+func containsAny(s string, words []string) bool {
+	s = strings.ToLower(s)
+	for _, word := range words {
+		if strings.Contains(s, strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Turns a snippet returned by Search into HTML: the text is escaped, since it can hold
+// anything (a syllabus explaining HTML, say), and only the matches are put in <mark>s
+func HighlightSnippet(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, snippetStart, "<mark>")
+	return strings.ReplaceAll(snippet, snippetStop, "</mark>")
+}
@@ -0,0 +1,90 @@
+package store
+
+import "testing"
+
+func TestMatchQuery(t *testing.T) {
+	tests := []struct {
+		text  string
+		match string
+		ok    bool
+	}{
+		{"lab", `"lab"*`, true},
+		{"  Lab 3  ", `"Lab" "3"*`, true},
+		{"midterm AND final", `"midterm" "AND" "final"*`, true},
+		{`name:"lab" OR NEAR(a b)`, `"name" "lab" "OR" "NEAR" "a" "b"*`, true},
+		{"o'brien's essay", `"o" "brien" "s" "essay"*`, true},
+		{"café résumé", `"café" "résumé"*`, true},
+		{"", "", false},
+		{`*"-^:()`, "", false},
+	}
+	for _, tt := range tests {
+		match, ok := MatchQuery(tt.text)
+		if match != tt.match || ok != tt.ok {
+			t.Errorf("MatchQuery(%q) = %q, %v, want %q, %v", tt.text, match, ok, tt.match, tt.ok)
+		}
+	}
+}
+
+func TestTSQuery(t *testing.T) {
+	tests := []struct {
+		match string
+		want  string
+	}{
+		{`"lab"*`, `'lab':*`},
+		{`"lab" "3"*`, `'lab' & '3':*`},
+		{`"midterm" "AND" "final"*`, `'midterm' & 'AND' & 'final':*`},
+		{`"café"*`, `'café':*`},
+	}
+	for _, tt := range tests {
+		if got := tsquery(tt.match); got != tt.want {
+			t.Errorf("tsquery(%q) = %q, want %q", tt.match, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"Write the \x02report\x03 for lab 3", "Write the <mark>report</mark> for lab 3"},
+		{"...use a \x02<span>\x03 tag...", "...use a <mark>&lt;span&gt;</mark> tag..."},
+		{`<script>alert("hi")</script> & more`, "&lt;script&gt;alert(&#34;hi&#34;)&lt;/script&gt; &amp; more"},
+		{"<mark>not ours</mark>", "&lt;mark&gt;not ours&lt;/mark&gt;"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := HighlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("HighlightSnippet(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+}
+
+func TestLikeSnippet(t *testing.T) {
+	long := "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen nineteen twenty"
+	tests := []struct {
+		text  string
+		words []string
+		want  string
+		ok    bool
+	}{
+		{"Write the report by Friday", []string{"report"}, "Write the \x02report\x03 by Friday", true},
+		{"Reports (REPORT), and a reporter", []string{"report"}, "\x02Reports\x03 (\x02REPORT\x03), and a \x02reporter\x03", true},
+		{"Never put <script> tags in", []string{"script"}, "Never put <\x02script\x03> tags in", true},
+		{"Lab 3 and lab 4", []string{"lab", "3"}, "\x02Lab\x03 \x023\x03 and \x02lab\x03 4", true},
+		{long, []string{"nineteen"}, "...five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen \x02nineteen\x03 twenty", true},
+		{long, []string{"three"}, "one two \x02three\x03 four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen...", true},
+		{long, []string{"missing"}, "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen...", false},
+		{"", []string{"lab"}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := likeSnippet(tt.text, tt.words)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("likeSnippet(%q, %q) = %q, %v, want %q, %v", tt.text, tt.words, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if got := likePattern(matchWords(`"lab" "3"*`)); got != "%lab%3%" {
+		t.Errorf("likePattern() = %q, want %%lab%%3%%", got)
+	}
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/johncmanuel/cpsc449-project2/db/migrations"
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+)
+
+func TestSQLiteDSN(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"./db/canvas.db", "./db/canvas.db?_foreign_keys=on"},
+		{"./db/canvas.db?_busy_timeout=5000", "./db/canvas.db?_busy_timeout=5000&_foreign_keys=on"},
+		{":memory:", ":memory:?_foreign_keys=on"},
+	}
+	for _, tt := range tests {
+		if got := sqliteDSN(tt.path); got != tt.want {
+			t.Errorf("sqliteDSN(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+// Opens a SQLite database the way the service does and applies every migration
+func openTestSQLite(t *testing.T) *Backend {
+	t.Helper()
+	ctx := context.Background()
+
+	b, err := Open(ctx, "sqlite:"+filepath.Join(t.TempDir(), "canvas.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(b.Close)
+
+	m, err := migrations.New(b.DB, b.Dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSQLiteCascades(t *testing.T) {
+	ctx := context.Background()
+	b := openTestSQLite(t)
+
+	if _, err := b.UpsertCourse(ctx, sqlite.UpsertCourseParams{ID: 449, Name: "CPSC 449"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int64{1, 2} {
+		if _, err := b.UpsertAssignment(ctx, sqlite.UpsertAssignmentParams{ID: id, CourseID: 449, Name: "Lab", Published: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.UpsertAssignmentOverride(ctx, sqlite.UpsertAssignmentOverrideParams{
+			AssignmentID: id,
+			Length:       sql.NullInt64{Int64: 30, Valid: true},
+			CanvasName:   "Lab",
+			UpdatedAt:    time.Now().UTC(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deleting an assignment (as a sync does once it's gone from Canvas) takes its
+	// overrides with it
+	if err := b.DeleteAssignment(ctx, sqlite.DeleteAssignmentParams{ID: 1, CourseID: 449}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.GetAssignmentOverride(ctx, 1); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("override of the deleted assignment: %v, want sql.ErrNoRows", err)
+	}
+
+	// And deleting the course takes the rest
+	if err := b.DeleteCourse(ctx, 449); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.GetAssignment(ctx, sqlite.GetAssignmentParams{ID: 2, CourseID: 449}); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("assignment of the deleted course: %v, want sql.ErrNoRows", err)
+	}
+	if _, err := b.GetAssignmentOverride(ctx, 2); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("override of the deleted course: %v, want sql.ErrNoRows", err)
+	}
+
+	// Rows can't point at a course that doesn't exist either
+	if _, err := b.UpsertAssignment(ctx, sqlite.UpsertAssignmentParams{ID: 3, CourseID: 404, Name: "Orphan"}); err == nil {
+		t.Error("inserting an assignment of a missing course succeeded")
+	}
+}
+
+func TestCreateTaskIDs(t *testing.T) {
+	ctx := context.Background()
+	b := openTestSQLite(t)
+
+	create := func() int64 {
+		t.Helper()
+		task, err := b.CreateTask(ctx, sqlite.CreateTaskParams{CourseID: 0, Name: "Study", Source: SourceManual})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return task.ID
+	}
+	if id := create(); id != -1 {
+		t.Errorf("first task got ID %d, want -1", id)
+	}
+	last := create()
+	if last != -2 {
+		t.Errorf("second task got ID %d, want -2", last)
+	}
+	// The ID of a deleted task isn't handed out again
+	if _, err := b.DeleteTask(ctx, last); err != nil {
+		t.Fatal(err)
+	}
+	if id := create(); id != -3 {
+		t.Errorf("task after a delete got ID %d, want -3", id)
+	}
+
+	// A database that had tasks before 0005 carries on below the lowest one
+	m, err := migrations.New(b.DB, b.Dialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.DB.ExecContext(ctx, "INSERT INTO assignments (id, course_id, name, source) VALUES (-10, 0, 'Old task', 'manual')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if id := create(); id != -11 {
+		t.Errorf("task after upgrading got ID %d, want -11", id)
+	}
+}
@@ -0,0 +1,83 @@
+package syllabus
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Returned for the files text can't be extracted from, e.g. PDFs
+var ErrUnsupported = errors.New("unsupported file type")
+
+// Elements whose text never shows up on the page
+var hiddenElements = map[string]bool{
+	"head":     true,
+	"script":   true,
+	"style":    true,
+	"template": true,
+	"noscript": true,
+}
+
+// Extracts the text of an uploaded syllabus so it can be searched, picking the format
+// from the file's extension: plain text and Markdown are kept as is, HTML pages (like
+// the syllabus page saved from Canvas) are stripped of their markup.
+func ExtractText(filename string, r io.Reader) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".md", ".markdown":
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		if !utf8.Valid(b) {
+			return "", fmt.Errorf("%s isn't UTF-8 text", filename)
+		}
+		return strings.TrimSpace(string(b)), nil
+	case ".html", ".htm":
+		return htmlText(r)
+	default:
+		return "", ErrUnsupported
+	}
+}
+
+// The text of an HTML fragment without its markup, e.g. an assignment's description
+// from Canvas
+func HTMLText(s string) string {
+	// Reading from a string can't fail
+	text, _ := htmlText(strings.NewReader(s))
+	return text
+}
+
+// Joins the text nodes of an HTML document, collapsing the whitespace between them
+func htmlText(r io.Reader) (string, error) {
+	var (
+		words  []string
+		hidden int
+	)
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return "", err
+			}
+			return strings.Join(words, " "), nil
+		case html.StartTagToken:
+			if name, _ := z.TagName(); hiddenElements[string(name)] {
+				hidden++
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); hiddenElements[string(name)] && hidden > 0 {
+				hidden--
+			}
+		case html.TextToken:
+			if hidden == 0 {
+				words = append(words, strings.Fields(string(z.Text()))...)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package syllabus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExtractText(t *testing.T) {
+	tests := []struct {
+		filename string
+		content  string
+		want     string
+		err      error
+	}{
+		{"syllabus.txt", "  CPSC 449\nOffice hours: MW 2-3pm\n", "CPSC 449\nOffice hours: MW 2-3pm", nil},
+		{"SYLLABUS.MD", "# Grading\n\n- Projects 60%", "# Grading\n\n- Projects 60%", nil},
+		{"notes.markdown", "<b>kept</b> as is", "<b>kept</b> as is", nil},
+		{
+			"syllabus.html",
+			`<html><head><title>Syllabus</title><style>p { color: red }</style></head>
+			<body><h1>CPSC&nbsp;449</h1><p class="intro">Web   back-end <em>engineering</em>.</p>
+			<script>var grade = "A";</script><p>Tom &amp; Jerry</p></body></html>`,
+			"CPSC 449 Web back-end engineering . Tom & Jerry",
+			nil,
+		},
+		{"page.htm", "<template><p>hidden</p></template><noscript>no js</noscript>Shown", "Shown", nil},
+		{"syllabus.pdf", "%PDF-1.7", "", ErrUnsupported},
+		{"syllabus", "no extension", "", ErrUnsupported},
+	}
+	for _, tt := range tests {
+		got, err := ExtractText(tt.filename, strings.NewReader(tt.content))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("ExtractText(%q) error = %v, want %v", tt.filename, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExtractText(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+
+	if _, err := ExtractText("latin1.txt", strings.NewReader("caf\xe9")); err == nil {
+		t.Error("ExtractText() of text that isn't UTF-8 succeeded")
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	tests := []struct {
+		html string
+		want string
+	}{
+		{`<p><span class="x" style="color: red">Write the report</span></p>`, "Write the report"},
+		{"<ul><li>Part 1</li><li>Part 2</li></ul>", "Part 1 Part 2"},
+		{"a &lt;span&gt; tag", "a <span> tag"},
+		{"Plain text", "Plain text"},
+		{"<p>unclosed", "unclosed"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := HTMLText(tt.html); got != tt.want {
+			t.Errorf("HTMLText(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+)
+
+// Types of assignment_events written by the sync
+const (
+	EventCreated           = "created"
+	EventDueDateChanged    = "due_date_changed"
+	EventRenamed           = "renamed"
+	EventRemovedFromCanvas = "removed_from_canvas"
+)
+
+// Compares an assignment from Canvas against the stored one (nil if it's new) and
+// returns the events describing what changed
+func diffAssignment(old *sqlite.Assignment, updated sqlite.UpsertAssignmentParams, now time.Time) []sqlite.CreateAssignmentEventParams {
+	event := func(eventType string, oldValue, newValue sql.NullString) sqlite.CreateAssignmentEventParams {
+		return sqlite.CreateAssignmentEventParams{
+			AssignmentID: updated.ID,
+			CourseID:     updated.CourseID,
+			EventType:    eventType,
+			OldValue:     oldValue,
+			NewValue:     newValue,
+			CreatedAt:    now,
+		}
+	}
+
+	if old == nil {
+		return []sqlite.CreateAssignmentEventParams{
+			event(EventCreated, sql.NullString{}, nullString(updated.Name)),
+		}
+	}
+
+	var events []sqlite.CreateAssignmentEventParams
+	if old.Name != updated.Name {
+		events = append(events, event(EventRenamed, nullString(old.Name), nullString(updated.Name)))
+	}
+	if !sameTime(old.DueDate, updated.DueDate) {
+		events = append(events, event(EventDueDateChanged, formatNullTime(old.DueDate), formatNullTime(updated.DueDate)))
+	}
+	return events
+}
+
+// Whether any of the assignment's other metadata changed on Canvas
+func metadataChanged(old sqlite.Assignment, updated sqlite.UpsertAssignmentParams) bool {
+	return old.PointsPossible != updated.PointsPossible ||
+		old.SubmissionTypes != updated.SubmissionTypes ||
+		!sameTime(old.UnlockAt, updated.UnlockAt) ||
+		!sameTime(old.LockAt, updated.LockAt) ||
+		old.HtmlUrl != updated.HtmlUrl ||
+		old.Published != updated.Published ||
+		old.AssignmentGroupID != updated.AssignmentGroupID ||
+		old.Description != updated.Description
+}
+
+// Whether the fields the user can override differ from the stored ones, which happens
+// when an override hasn't made it to the stored assignment yet
+func localFieldsChanged(old sqlite.Assignment, updated sqlite.UpsertAssignmentParams) bool {
+	return old.Name != updated.Name ||
+		!sameTime(old.DueDate, updated.DueDate) ||
+		old.Difficulty != updated.Difficulty ||
+		old.Length != updated.Length
+}
+
+func removedEvent(old sqlite.Assignment, now time.Time) sqlite.CreateAssignmentEventParams {
+	return sqlite.CreateAssignmentEventParams{
+		AssignmentID: old.ID,
+		CourseID:     old.CourseID,
+		EventType:    EventRemovedFromCanvas,
+		OldValue:     nullString(old.Name),
+		CreatedAt:    now,
+	}
+}
+
+// Saves the events. They're only informational, so failing to save them shouldn't
+// fail the sync either.
+func (s *Syncer) recordEvents(ctx context.Context, events ...sqlite.CreateAssignmentEventParams) error {
+	for _, e := range events {
+		if err := s.q.CreateAssignmentEvent(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sameTime(a, b sql.NullTime) bool {
+	return a.Valid == b.Valid && a.Time.Equal(b.Time)
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func formatNullTime(t sql.NullTime) sql.NullString {
+	if !t.Valid {
+		return sql.NullString{}
+	}
+	return nullString(t.Time.UTC().Format(time.RFC3339))
+}
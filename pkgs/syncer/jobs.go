@@ -0,0 +1,87 @@
+package syncer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+)
+
+// Status of an async sync job
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// A sync running in the background. Jobs are kept in the cache so any replica can
+// report on them when it's Redis, not just the one running it.
+type Job struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	Report    *Report   `json:"report,omitempty"`
+	Error     string    `json:"error,omitempty"`
+}
+
+func jobKey(id string) string {
+	return redis.Key(redis.KindSyncJob, id)
+}
+
+// Starts a sync in the background and returns right away with the job to poll
+func (s *Syncer) StartJob(ctx context.Context, req Request) (Job, error) {
+	r := redis.GetInstance()
+	if r.Name() == redis.CacheNone {
+		return Job{}, ErrJobsUnavailable
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return Job{}, err
+	}
+	job := Job{
+		ID:        hex.EncodeToString(b),
+		Status:    JobRunning,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := r.SetWithExpirationContext(ctx, jobKey(job.ID), job, redis.KindSyncJob.TTL()); err != nil {
+		return Job{}, fmt.Errorf("failed to save sync job: %v", err)
+	}
+
+	go func() {
+		// Not tied to the request that started the job
+		ctx := context.WithoutCancel(ctx)
+		report, err := s.Run(ctx, req)
+		job.Report = &report
+		job.Status = JobSucceeded
+		if err != nil {
+			job.Status = JobFailed
+			job.Error = err.Error()
+		}
+		if err := r.SetWithExpirationContext(ctx, jobKey(job.ID), job, redis.KindSyncJob.TTL()); err != nil {
+			fmt.Printf("Error saving sync job %s: %v\n", job.ID, err)
+		}
+	}()
+
+	return job, nil
+}
+
+// Looks up a job started with StartJob, returns false if it doesn't exist (anymore)
+func GetJob(ctx context.Context, id string) (Job, bool) {
+	val, err := redis.GetInstance().GetContext(ctx, jobKey(id))
+	if err != nil {
+		return Job{}, false
+	}
+
+	var job Job
+	if err := json.Unmarshal([]byte(val), &job); err != nil {
+		fmt.Printf("Error unmarshalling sync job: %v\n", err)
+		return Job{}, false
+	}
+	return job, true
+}
@@ -0,0 +1,583 @@
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/canvas"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/syllabus"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/utils"
+)
+
+// Where a sync run came from, stored with each run
+const (
+	SourceScheduled = "scheduled"
+	SourceManual    = "manual"
+)
+
+// Canvas assignment buckets a sync can be limited to
+const (
+	BucketUpcoming = "upcoming"
+	BucketFuture   = "future"
+)
+
+// Redis key of the lock held while syncing, shared by every replica
+var lockKey = redis.LockKey("sync")
+
+// Returned when another sync (possibly on another replica) holds the lock
+var ErrAlreadyRunning = errors.New("a sync is already running")
+
+// Returned by StartJob in cache-less mode, there'd be nowhere to keep the job
+var ErrJobsUnavailable = errors.New("async syncs need a cache")
+
+// Returned when RequireSharedLock is set and Redis is down, so the lock would only keep
+// this replica from syncing twice
+var ErrLockNotShared = errors.New("the sync lock isn't shared with the other replicas while Redis is down")
+
+type Options struct {
+	// Time between scheduled syncs, 0 disables them
+	Interval time.Duration
+	// Random extra delay added to each interval so replicas don't all wake up at once
+	Jitter time.Duration
+	// Whether to sync as soon as the syncer starts
+	RunOnStart bool
+	// How long the lock is held at most, in case the replica holding it dies. Should be
+	// longer than a full sync takes.
+	LockTTL time.Duration
+	// Refuse to sync while the lock is only held within this process (Redis is down),
+	// for deployments with several replicas. Otherwise it's only logged.
+	RequireSharedLock bool
+}
+
+// Keeps the database in sync with Canvas, either in the background or on demand
+type Syncer struct {
+	client *canvas.CanvasClient
+	q      store.Store
+	opts   Options
+}
+
+// What to sync
+type Request struct {
+	Source string `json:"source"`
+	// Enrollment term to sync, 0 for the current term(s)
+	TermID int `json:"term_id,omitempty"`
+	// Only sync this course when set, TermID is ignored then
+	CourseID int `json:"course_id,omitempty"`
+	// Only sync the assignments in this Canvas bucket (BucketUpcoming or BucketFuture).
+	// The other assignments are left alone, so nothing gets removed.
+	Bucket string `json:"bucket,omitempty"`
+	// Ignore the stored ETags and download every course again
+	Full bool `json:"full,omitempty"`
+}
+
+// What a sync did, overall and for each course
+type Report struct {
+	Request
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	ElapsedMs  int64          `json:"elapsed_ms"`
+	Courses    []CourseReport `json:"courses"`
+	Inserted   int            `json:"inserted"`
+	Updated    int            `json:"updated"`
+	Unchanged  int            `json:"unchanged"`
+	Removed    int            `json:"removed"`
+	// Number of courses skipped since their assignments didn't change on Canvas
+	Skipped int `json:"skipped"`
+	// Number of courses that failed to sync, see their reports for details
+	Failed int `json:"failed"`
+}
+
+type CourseReport struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	Inserted  int    `json:"inserted"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	Removed   int    `json:"removed"`
+	Skipped   bool   `json:"skipped,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func New(client *canvas.CanvasClient, q store.Store, opts Options) *Syncer {
+	return &Syncer{
+		client: client,
+		q:      q,
+		opts:   opts,
+	}
+}
+
+// Runs the scheduled syncs in a background goroutine until ctx is cancelled
+func (s *Syncer) Start(ctx context.Context) {
+	if s.opts.Interval <= 0 && !s.opts.RunOnStart {
+		return
+	}
+	go s.loop(ctx)
+}
+
+func (s *Syncer) loop(ctx context.Context) {
+	if s.opts.RunOnStart {
+		s.runScheduled(ctx)
+	}
+	if s.opts.Interval <= 0 {
+		return
+	}
+
+	for {
+		wait := s.opts.Interval
+		if s.opts.Jitter > 0 {
+			wait += rand.N(s.opts.Jitter)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runScheduled(ctx)
+	}
+}
+
+func (s *Syncer) runScheduled(ctx context.Context) {
+	report, err := s.Run(ctx, Request{Source: SourceScheduled})
+	if errors.Is(err, ErrAlreadyRunning) {
+		fmt.Println("Skipping scheduled sync, another one is already running")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Scheduled sync failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Scheduled sync finished in %dms: %d courses, %d inserted, %d updated, %d unchanged, %d removed, %d skipped, %d failed\n",
+		report.ElapsedMs, len(report.Courses), report.Inserted, report.Updated, report.Unchanged, report.Removed, report.Skipped, report.Failed)
+}
+
+// Syncs from Canvas while holding the distributed lock, and records the run in the
+// sync_runs table
+func (s *Syncer) Run(ctx context.Context, req Request) (Report, error) {
+	report := Report{
+		Request:   req,
+		StartedAt: time.Now().UTC(),
+		Courses:   []CourseReport{},
+	}
+
+	r := redis.GetInstance()
+	if !r.Shared() {
+		if s.opts.RequireSharedLock {
+			return report, ErrLockNotShared
+		}
+		fmt.Printf("Syncing with the %s cache, other replicas may sync at the same time\n", r.Name())
+	}
+	token, ok, err := r.AcquireLockContext(ctx, lockKey, s.opts.LockTTL)
+	if err != nil {
+		return report, fmt.Errorf("failed to acquire sync lock: %v", err)
+	}
+	if !ok {
+		return report, ErrAlreadyRunning
+	}
+	defer func() {
+		// Release the lock even if ctx was cancelled
+		if err := r.ReleaseLockContext(context.WithoutCancel(ctx), lockKey, token); err != nil {
+			fmt.Printf("Error releasing sync lock: %v\n", err)
+		}
+	}()
+
+	run, err := s.q.CreateSyncRun(ctx, sqlite.CreateSyncRunParams{
+		Source:    req.Source,
+		StartedAt: report.StartedAt,
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to record sync run: %v", err)
+	}
+	s.invalidate(ctx, redis.SyncRunsInvalidation())
+
+	syncErr := s.sync(ctx, &report)
+	report.FinishedAt = time.Now().UTC()
+	report.ElapsedMs = report.FinishedAt.Sub(report.StartedAt).Milliseconds()
+
+	// Record the run even if the request that triggered it went away
+	params := sqlite.FinishSyncRunParams{
+		ID:                run.ID,
+		FinishedAt:        sql.NullTime{Time: report.FinishedAt, Valid: true},
+		CoursesSynced:     int64(len(report.Courses) - report.Failed),
+		AssignmentsSynced: int64(report.Inserted + report.Updated + report.Unchanged),
+	}
+	if syncErr != nil {
+		params.Error = sql.NullString{String: syncErr.Error(), Valid: true}
+	} else if report.Failed > 0 {
+		// Partial failures are detailed in the report, the run itself went through
+		msg := fmt.Sprintf("%d of %d courses failed to sync", report.Failed, len(report.Courses))
+		params.Error = sql.NullString{String: msg, Valid: true}
+	}
+	if err := s.q.FinishSyncRun(context.WithoutCancel(ctx), params); err != nil {
+		fmt.Printf("Error recording sync run: %v\n", err)
+	}
+	s.invalidate(context.WithoutCancel(ctx), redis.SyncRunsInvalidation())
+
+	return report, syncErr
+}
+
+// Works out which courses to sync, then syncs each one of them into the report
+func (s *Syncer) sync(ctx context.Context, report *Report) error {
+	var courses []canvas.Course
+	var err error
+	switch {
+	case report.CourseID != 0:
+		var course canvas.Course
+		course, err = s.client.GetCourseContext(ctx, report.CourseID)
+		courses = []canvas.Course{course}
+	case report.TermID != 0:
+		courses, err = s.client.GetCoursesForTermContext(ctx, report.TermID)
+	default:
+		courses, err = s.client.GetCurrentTermCoursesContext(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch courses: %v", err)
+	}
+
+	results, err := s.client.GetAssignmentsForCoursesQueryContext(ctx, courses, s.assignmentQueries(ctx, report.Request, courses))
+	courseErrs := canvas.CourseErrors(err)
+	if err != nil && len(courseErrs) == 0 {
+		return fmt.Errorf("failed to fetch assignments: %v", err)
+	}
+
+	// Courses that failed to fetch are only in fetchErrs
+	resultsByCourse := make(map[int]canvas.CourseAssignments, len(results))
+	for _, result := range results {
+		resultsByCourse[result.Course.ID] = result
+	}
+	fetchErrs := make(map[int]error, len(courseErrs))
+	for _, courseErr := range courseErrs {
+		fetchErrs[courseErr.CourseID] = courseErr.Err
+	}
+
+	for _, course := range courses {
+		courseReport := CourseReport{ID: course.ID, Name: course.Name}
+
+		if err, failed := fetchErrs[course.ID]; failed {
+			courseReport.Error = fmt.Sprintf("failed to fetch assignments: %v", err)
+		} else {
+			if err := s.syncCourse(ctx, resultsByCourse[course.ID], report.Bucket, &courseReport); err != nil {
+				courseReport.Error = err.Error()
+			}
+			// Even a course that failed halfway may have changed, and a course
+			// Canvas skipped may still have been renamed
+			s.invalidate(ctx, redis.CourseInvalidation(int64(course.ID)))
+		}
+		if courseReport.Error != "" {
+			report.Failed++
+		}
+		if courseReport.Skipped {
+			report.Skipped++
+		}
+
+		report.Inserted += courseReport.Inserted
+		report.Updated += courseReport.Updated
+		report.Unchanged += courseReport.Unchanged
+		report.Removed += courseReport.Removed
+		report.Courses = append(report.Courses, courseReport)
+	}
+
+	return nil
+}
+
+// Evicts what the sync changed from the cache, on every replica. The database is
+// already up to date, so a failure only means stale reads until the entries expire.
+func (s *Syncer) invalidate(ctx context.Context, inv redis.Invalidation) {
+	if err := redis.GetInstance().InvalidateContext(ctx, inv); err != nil {
+		fmt.Printf("Error invalidating cache: %v\n", err)
+	}
+}
+
+// Builds the Canvas query for each course. Full syncs send the ETag stored by the
+// previous sync so unchanged courses can be skipped. Bucket syncs don't, since a bucket
+// only covers part of the list.
+func (s *Syncer) assignmentQueries(ctx context.Context, req Request, courses []canvas.Course) map[int]canvas.AssignmentQuery {
+	queries := make(map[int]canvas.AssignmentQuery, len(courses))
+	for _, course := range courses {
+		query := canvas.AssignmentQuery{Bucket: req.Bucket}
+		if req.Bucket == "" && !req.Full {
+			state, err := s.q.GetCourseSyncState(ctx, int64(course.ID))
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				fmt.Printf("Error loading sync state of course %d: %v\n", course.ID, err)
+			}
+			query.ETag = state.Etag.String
+		}
+		queries[course.ID] = query
+	}
+	return queries
+}
+
+// Upserts the course, then compares its assignments from Canvas against the stored
+// ones: new ones are inserted, changed ones updated and the ones that are no longer
+// on Canvas removed. Courses Canvas says haven't changed are skipped. Once a full
+// (non-bucket) sync of the course went through, its ETag is stored for the next sync.
+func (s *Syncer) syncCourse(ctx context.Context, result canvas.CourseAssignments, bucket string, report *CourseReport) error {
+	course := result.Course
+	// The course has to exist before its assignments can reference it
+	if _, err := s.q.UpsertCourse(ctx, courseParams(course)); err != nil {
+		return fmt.Errorf("failed to save course: %v", err)
+	}
+
+	if result.NotModified {
+		report.Skipped = true
+	} else if err := s.syncAssignments(ctx, course, result.Assignments, bucket, report); err != nil {
+		return err
+	}
+
+	if bucket != "" {
+		return nil
+	}
+	err := s.q.UpsertCourseSyncState(ctx, sqlite.UpsertCourseSyncStateParams{
+		CourseID:     int64(course.ID),
+		Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+		LastSyncedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save sync state: %v", err)
+	}
+	return nil
+}
+
+// Applies the course's assignments from Canvas to the stored ones
+func (s *Syncer) syncAssignments(ctx context.Context, course canvas.Course, assignments []canvas.Assignment, bucket string, report *CourseReport) error {
+	stored, err := s.q.ListCourseAssignments(ctx, int64(course.ID))
+	if err != nil {
+		return fmt.Errorf("failed to list stored assignments: %v", err)
+	}
+	// Tasks added to the course by hand aren't on Canvas, leave them alone
+	existing := make(map[int64]sqlite.Assignment, len(stored))
+	for _, a := range stored {
+		if a.Source == store.SourceCanvas {
+			existing[a.ID] = a
+		}
+	}
+
+	now := time.Now().UTC()
+	var errs []error
+	for _, assignment := range assignments {
+		delete(existing, int64(assignment.ID))
+
+		change, events, err := s.syncAssignment(ctx, course, assignment, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		switch change {
+		case assignmentInserted:
+			report.Inserted++
+		case assignmentUpdated:
+			report.Updated++
+		default:
+			report.Unchanged++
+		}
+		if err := s.recordEvents(ctx, events...); err != nil {
+			fmt.Printf("Error recording events for assignment %d: %v\n", assignment.ID, err)
+		}
+
+		// Submissions change independently of the assignment, so always store them
+		if err := s.saveSubmission(ctx, assignment); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// Whatever is left was deleted (or unpublished) on Canvas, unless the sync only
+	// looked at a bucket
+	if bucket != "" {
+		clear(existing)
+	}
+	for id, old := range existing {
+		params := sqlite.DeleteAssignmentParams{CourseID: int64(course.ID), ID: id}
+		if err := s.q.DeleteAssignment(ctx, params); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove assignment %d: %v", id, err))
+			continue
+		}
+		report.Removed++
+
+		if err := s.recordEvents(ctx, removedEvent(old, now)); err != nil {
+			fmt.Printf("Error recording events for assignment %d: %v\n", id, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// What syncing an assignment did to the stored one
+type assignmentChange int
+
+const (
+	assignmentUnchanged assignmentChange = iota
+	assignmentInserted
+	assignmentUpdated
+)
+
+// Saves an assignment from Canvas, keeping the user's estimates and overrides. The
+// stored assignment and its override are read again and written back in one
+// transaction, so an override saved while the course syncs either comes before the
+// read (and is kept) or makes the write fail, it's never overwritten with Canvas's
+// values. Returns the events to record once it's saved.
+func (s *Syncer) syncAssignment(ctx context.Context, course canvas.Course, assignment canvas.Assignment, now time.Time) (assignmentChange, []sqlite.CreateAssignmentEventParams, error) {
+	var change assignmentChange
+	var events []sqlite.CreateAssignmentEventParams
+	err := s.q.WithTx(ctx, func(q store.Store) error {
+		params := assignmentParams(course.ID, assignment)
+
+		var old *sqlite.Assignment
+		stored, err := q.GetAssignment(ctx, sqlite.GetAssignmentParams{ID: params.ID, CourseID: params.CourseID})
+		if err == nil && stored.Source == store.SourceCanvas {
+			old = &stored
+			// Canvas knows nothing about our estimates, keep them
+			params.Difficulty = old.Difficulty
+			params.Length = old.Length
+		} else if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to read assignment %d: %v", assignment.ID, err)
+		}
+		override, err := q.GetAssignmentOverride(ctx, params.ID)
+		hasOverride := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to read the overrides of assignment %d: %v", assignment.ID, err)
+		}
+
+		// Only the name and due date are worth an event, the rest of the metadata is
+		// just kept up to date. Events are about what changed on Canvas, so an
+		// overridden assignment is compared against Canvas's values from last time.
+		previous := old
+		if hasOverride && old != nil {
+			fromCanvas := *old
+			fromCanvas.Name = override.CanvasName
+			fromCanvas.DueDate = override.CanvasDueDate
+			previous = &fromCanvas
+		}
+		events = diffAssignment(previous, params, now)
+
+		// The user's overrides win over Canvas, but keep Canvas's values around for
+		// when they're cleared
+		if hasOverride {
+			if override.CanvasName != params.Name || !sameTime(override.CanvasDueDate, params.DueDate) {
+				err := q.UpdateAssignmentOverrideCanvas(ctx, sqlite.UpdateAssignmentOverrideCanvasParams{
+					AssignmentID:  params.ID,
+					CanvasName:    params.Name,
+					CanvasDueDate: params.DueDate,
+				})
+				if err != nil {
+					return fmt.Errorf("failed to save Canvas values for overridden assignment %d: %v", assignment.ID, err)
+				}
+			}
+			params = applyOverride(params, override)
+		}
+
+		switch {
+		case old == nil:
+			change = assignmentInserted
+		case len(events) > 0 || metadataChanged(*old, params) || localFieldsChanged(*old, params):
+			change = assignmentUpdated
+		default:
+			change = assignmentUnchanged
+			return nil
+		}
+		if _, err := q.UpsertAssignment(ctx, params); err != nil {
+			return fmt.Errorf("failed to save assignment %d: %v", assignment.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return assignmentUnchanged, nil, err
+	}
+	return change, events, nil
+}
+
+// Stores the user's submission for the assignment, if Canvas sent one
+func (s *Syncer) saveSubmission(ctx context.Context, assignment canvas.Assignment) error {
+	sub := assignment.Submission
+	if sub == nil {
+		return nil
+	}
+
+	params := sqlite.UpsertSubmissionParams{
+		AssignmentID:  int64(assignment.ID),
+		WorkflowState: sub.WorkflowState,
+		SubmittedAt:   utils.ConvertToNullTime(sub.SubmittedAt),
+		Late:          sub.Late,
+		Missing:       sub.Missing,
+		Excused:       sub.Excused,
+	}
+	if sub.Score != nil {
+		params.Score = sql.NullFloat64{Float64: *sub.Score, Valid: true}
+	}
+	if err := s.q.UpsertSubmission(ctx, params); err != nil {
+		return fmt.Errorf("failed to save submission for assignment %d: %v", assignment.ID, err)
+	}
+	return nil
+}
+
+// Maps a Canvas assignment to the params for upserting it
+func assignmentParams(courseID int, assignment canvas.Assignment) sqlite.UpsertAssignmentParams {
+	params := sqlite.UpsertAssignmentParams{
+		ID:                int64(assignment.ID),
+		CourseID:          int64(courseID),
+		Name:              assignment.Name,
+		DueDate:           utils.ConvertToNullTime(assignment.DueAt),
+		SubmissionTypes:   sql.NullString{String: strings.Join(assignment.SubmissionTypes, ","), Valid: len(assignment.SubmissionTypes) > 0},
+		UnlockAt:          utils.ConvertToNullTime(assignment.UnlockAt),
+		LockAt:            utils.ConvertToNullTime(assignment.LockAt),
+		HtmlUrl:           sql.NullString{String: assignment.HTMLURL, Valid: assignment.HTMLURL != ""},
+		Published:         assignment.Published,
+		AssignmentGroupID: sql.NullInt64{Int64: int64(assignment.AssignmentGroupID), Valid: assignment.AssignmentGroupID != 0},
+		Description:       sql.NullString{String: assignment.Description, Valid: assignment.Description != ""},
+		// The description is HTML, /search indexes its text
+		DescriptionText: sql.NullString{String: syllabus.HTMLText(assignment.Description), Valid: assignment.Description != ""},
+	}
+	if assignment.PointsPossible != nil {
+		params.PointsPossible = sql.NullFloat64{Float64: *assignment.PointsPossible, Valid: true}
+	}
+	return params
+}
+
+// Puts the fields the user overrode over the ones from Canvas
+func applyOverride(params sqlite.UpsertAssignmentParams, override sqlite.AssignmentOverride) sqlite.UpsertAssignmentParams {
+	if override.Name.Valid {
+		params.Name = override.Name.String
+	}
+	if override.DueDate.Valid {
+		params.DueDate = override.DueDate
+	}
+	if override.Difficulty.Valid {
+		params.Difficulty = override.Difficulty
+	}
+	if override.Length.Valid {
+		params.Length = override.Length
+	}
+	return params
+}
+
+// Maps a Canvas course to the params for upserting it. Courses often don't have
+// their own start/end dates, so fall back to the term's dates.
+func courseParams(course canvas.Course) sqlite.UpsertCourseParams {
+	startAt := utils.ConvertToNullTime(course.StartAt)
+	if !startAt.Valid {
+		startAt = utils.ConvertToNullTime(course.Term.StartAt)
+	}
+	endAt := utils.ConvertToNullTime(course.EndAt)
+	if !endAt.Valid {
+		endAt = utils.ConvertToNullTime(course.Term.EndAt)
+	}
+
+	return sqlite.UpsertCourseParams{
+		ID:       int64(course.ID),
+		Name:     course.Name,
+		TermID:   sql.NullInt64{Int64: int64(course.Term.ID), Valid: course.Term.ID != 0},
+		TermName: sql.NullString{String: course.Term.Name, Valid: course.Term.Name != ""},
+		StartAt:  startAt,
+		EndAt:    endAt,
+	}
+}
@@ -0,0 +1,134 @@
+package syncer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/johncmanuel/cpsc449-project2/db/migrations"
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/canvas"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+func newTestSyncer(t *testing.T) *Syncer {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file::memory:?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: gets its own database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrations.New(db, migrations.DialectSQLite)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return &Syncer{q: store.NewSQLite(db)}
+}
+
+// Fails to save assignments, after the rest of the sync's writes went through
+type failingUpsert struct {
+	store.Store
+}
+
+func (f failingUpsert) UpsertAssignment(ctx context.Context, arg sqlite.UpsertAssignmentParams) (sqlite.Assignment, error) {
+	return sqlite.Assignment{}, errors.New("database is locked")
+}
+
+func (f failingUpsert) WithTx(ctx context.Context, fn func(store.Store) error) error {
+	return f.Store.WithTx(ctx, func(q store.Store) error {
+		return fn(failingUpsert{q})
+	})
+}
+
+func TestSyncAssignment(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSyncer(t)
+	course := canvas.Course{ID: 449, Name: "CPSC 449"}
+	if _, err := s.q.UpsertCourse(ctx, courseParams(course)); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	lab := canvas.Assignment{ID: 1, Name: "Lab 1", DueAt: "2030-01-01T00:00:00Z", Published: true}
+
+	change, _, err := s.syncAssignment(ctx, course, lab, now)
+	if err != nil || change != assignmentInserted {
+		t.Fatalf("first sync = %v, %v, want inserted", change, err)
+	}
+	change, events, err := s.syncAssignment(ctx, course, lab, now)
+	if err != nil || change != assignmentUnchanged || len(events) != 0 {
+		t.Fatalf("second sync = %v, %v, %v, want unchanged", change, events, err)
+	}
+
+	// The user renamed it and estimated it
+	if err := s.q.UpsertAssignmentOverride(ctx, sqlite.UpsertAssignmentOverrideParams{
+		AssignmentID:  1,
+		Name:          sql.NullString{String: "My lab", Valid: true},
+		Length:        sql.NullInt64{Int64: 90, Valid: true},
+		CanvasName:    lab.Name,
+		CanvasDueDate: sql.NullTime{Time: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		UpdatedAt:     now,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.q.UpdateAssignment(ctx, sqlite.UpdateAssignmentParams{
+		ID:         1,
+		Name:       "My lab",
+		DueDate:    sql.NullTime{Time: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC), Valid: true},
+		Difficulty: sql.NullInt64{Int64: 7, Valid: true},
+		Length:     sql.NullInt64{Int64: 90, Valid: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Canvas renames it too, the user's name wins and Canvas's is kept for later
+	lab.Name = "Lab 1: Setup"
+	change, events, err = s.syncAssignment(ctx, course, lab, now)
+	if err != nil || change != assignmentUpdated || len(events) != 1 {
+		t.Fatalf("sync after the rename = %v, %v, %v, want updated with one event", change, events, err)
+	}
+	a, err := s.q.GetAssignment(ctx, sqlite.GetAssignmentParams{ID: 1, CourseID: 449})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "My lab" || a.Difficulty.Int64 != 7 || a.Length.Int64 != 90 {
+		t.Errorf("assignment = %q, difficulty %v, length %v, want the user's", a.Name, a.Difficulty, a.Length)
+	}
+	o, err := s.q.GetAssignmentOverride(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.CanvasName != "Lab 1: Setup" {
+		t.Errorf("Canvas name = %q, want Lab 1: Setup", o.CanvasName)
+	}
+
+	// Canvas's new values aren't kept when the assignment fails to save
+	lab.Name = "Lab 1: Renamed again"
+	failing := &Syncer{q: failingUpsert{s.q}}
+	if _, _, err := failing.syncAssignment(ctx, course, lab, now); err == nil {
+		t.Fatal("sync succeeded without saving the assignment")
+	}
+	if o, err := s.q.GetAssignmentOverride(ctx, 1); err != nil || o.CanvasName != "Lab 1: Setup" {
+		t.Errorf("Canvas name after the failed sync = %q, %v, want Lab 1: Setup", o.CanvasName, err)
+	}
+}
+
+func TestRunRequireSharedLock(t *testing.T) {
+	s := newTestSyncer(t)
+	s.opts.RequireSharedLock = true
+	redis.SetInstance(redis.NewMemoryCache(100))
+
+	if _, err := s.Run(context.Background(), Request{Source: SourceManual}); !errors.Is(err, ErrLockNotShared) {
+		t.Errorf("Run() with an in-process cache = %v, want ErrLockNotShared", err)
+	}
+}
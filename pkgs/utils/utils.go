@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -24,6 +25,93 @@ func GetEnv(key string) string {
 	return env
 }
 
+// Same as GetEnv but for optional variables, falls back to def when the variable isn't set
+func GetEnvOrDefault(key, def string) string {
+	env := os.Getenv(key)
+	if env == "" {
+		return def
+	}
+	return env
+}
+
+// Reads an optional integer variable, falls back to def when the variable isn't set
+func GetEnvInt(key string, def int) int {
+	env := os.Getenv(key)
+	if env == "" {
+		return def
+	}
+	i, err := strconv.Atoi(env)
+	if err != nil {
+		panic("Environment variable is not an integer: " + key)
+	}
+	return i
+}
+
+// Reads an optional duration variable (e.g. "1h30m"), falls back to def when the
+// variable isn't set
+func GetEnvDuration(key string, def time.Duration) time.Duration {
+	env := os.Getenv(key)
+	if env == "" {
+		return def
+	}
+	d, err := time.ParseDuration(env)
+	if err != nil {
+		panic("Environment variable is not a duration: " + key)
+	}
+	return d
+}
+
+// Reads an optional boolean variable, falls back to def when the variable isn't set
+func GetEnvBool(key string, def bool) bool {
+	env := os.Getenv(key)
+	if env == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(env)
+	if err != nil {
+		panic("Environment variable is not a boolean: " + key)
+	}
+	return b
+}
+
+// Reads an optional comma-separated list (e.g. "host1:26379,host2:26379"), returns nil
+// when the variable isn't set
+func GetEnvList(key string) []string {
+	env := os.Getenv(key)
+	if env == "" {
+		return nil
+	}
+	var list []string
+	for _, item := range strings.Split(env, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Reads an optional comma-separated list of name=duration pairs (e.g.
+// "assignment=5m,plan=1h"), returns nil when the variable isn't set
+func GetEnvDurations(key string) map[string]time.Duration {
+	list := GetEnvList(key)
+	if list == nil {
+		return nil
+	}
+	durations := make(map[string]time.Duration, len(list))
+	for _, item := range list {
+		name, val, ok := strings.Cut(item, "=")
+		if !ok {
+			panic("Environment variable is not a list of name=duration: " + key)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(val))
+		if err != nil {
+			panic("Environment variable has an invalid duration for " + name + ": " + key)
+		}
+		durations[strings.TrimSpace(name)] = d
+	}
+	return durations
+}
+
 func ConvertToNullTime(timestamp string) sql.NullTime {
 	parsedTime, err := time.Parse(time.RFC3339, timestamp)
 	if err != nil {
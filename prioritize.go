@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/llm"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// Cache key of the most recent plan, see redis.KindPlan for how long it's kept
+var latestPlanKey = redis.Key(redis.KindPlan, "latest")
+
+// Max number of upcoming assignments sent to the model by default
+const defaultPrioritizeLimit = 50
+
+// for OpenAI request
+type Assignment struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	CourseID   string `json:"course_id"`
+	DueDate    string `json:"due_date"`
+	Difficulty string `json:"difficulty,omitempty"`
+	Length     string `json:"length,omitempty"`
+	// Worth more points usually means more work
+	PointsPossible  float64 `json:"points_possible,omitempty"`
+	SubmissionTypes string  `json:"submission_types,omitempty"`
+}
+
+// One entry of the model's response
+type assignmentEstimate struct {
+	ID         int64  `json:"id"`
+	Difficulty int64  `json:"difficulty"`
+	Length     int64  `json:"length"`
+	Reason     string `json:"reason"`
+}
+
+type prioritizeResponse struct {
+	Assignments []assignmentEstimate `json:"assignments"`
+}
+
+type PrioritizedAssignment struct {
+	Rank       int    `json:"rank"`
+	ID         int64  `json:"id"`
+	CourseID   int64  `json:"course_id"`
+	Name       string `json:"name"`
+	DueDate    string `json:"due_date"`
+	Difficulty int64  `json:"difficulty"`
+	Length     int64  `json:"length"`
+	Reason     string `json:"reason"`
+}
+
+type PriorityPlan struct {
+	GeneratedAt time.Time               `json:"generated_at"`
+	Model       string                  `json:"model"`
+	Assignments []PrioritizedAssignment `json:"assignments"`
+}
+
+// Strict structured outputs only support a subset of JSON schema (no minimum/maximum),
+// so the ranges are described to the model and enforced after the fact.
+// https://platform.openai.com/docs/guides/structured-outputs#supported-schemas
+var prioritizeSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"assignments": map[string]interface{}{
+			"type":        "array",
+			"description": "Every given assignment, ordered from the one to work on first to the one to work on last",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "integer",
+						"description": "ID of the assignment",
+					},
+					"difficulty": map[string]interface{}{
+						"type":        "integer",
+						"description": "Estimated difficulty from 1 (trivial) to 10 (very hard)",
+					},
+					"length": map[string]interface{}{
+						"type":        "integer",
+						"description": "Estimated number of minutes needed to finish the assignment",
+					},
+					"reason": map[string]interface{}{
+						"type":        "string",
+						"description": "One short sentence explaining the position in the ranking",
+					},
+				},
+				"required":             []string{"id", "difficulty", "length", "reason"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"assignments"},
+	"additionalProperties": false,
+}
+
+const prioritizePrompt = `You help a college student plan their coursework.
+You are given a JSON list of upcoming assignments with their due dates (and points,
+submission types and previous estimates, if any). Estimate each assignment's difficulty and how long it will take,
+then rank all of them in the order the student should work on them, weighing how soon
+each one is due against how much work it needs. Include every assignment exactly once.`
+
+// Sends the upcoming assignments to the model, stores its difficulty/length estimates
+// and returns the resulting ranking. Assignments that were already turned in are left
+// out unless includeSubmitted is set.
+func PrioritizeAssignments(ctx context.Context, provider llm.Provider, q store.Store, limit int64, includeSubmitted bool) (PriorityPlan, error) {
+	plan := PriorityPlan{
+		GeneratedAt: time.Now().UTC(),
+		Model:       provider.Name(),
+		Assignments: []PrioritizedAssignment{},
+	}
+
+	var upcoming []sqlite.Assignment
+	var err error
+	dueDate := sql.NullTime{Time: plan.GeneratedAt, Valid: true}
+	if includeSubmitted {
+		upcoming, err = q.ListUpcomingAssignments(ctx, sqlite.ListUpcomingAssignmentsParams{
+			DueDate: dueDate,
+			Limit:   limit,
+		})
+	} else {
+		upcoming, err = q.ListUpcomingPendingAssignments(ctx, sqlite.ListUpcomingPendingAssignmentsParams{
+			DueDate: dueDate,
+			Limit:   limit,
+		})
+	}
+	if err != nil {
+		return plan, fmt.Errorf("failed to list upcoming assignments: %v", err)
+	}
+	if len(upcoming) == 0 {
+		return plan, nil
+	}
+
+	byID := make(map[int64]sqlite.Assignment, len(upcoming))
+	payload := make([]Assignment, 0, len(upcoming))
+	for _, a := range upcoming {
+		byID[a.ID] = a
+		payload = append(payload, toPromptAssignment(a))
+	}
+	input, err := json.Marshal(payload)
+	if err != nil {
+		return plan, fmt.Errorf("failed to serialize assignments: %v", err)
+	}
+
+	content, err := provider.CompleteJSON(ctx, llm.Request{
+		System: prioritizePrompt,
+		Prompt: string(input),
+	}, llm.JSONSchema{
+		Name:        llm.PrioritiesSchemaName,
+		Description: "Ranked assignments with difficulty and length estimates",
+		Schema:      prioritizeSchema,
+	})
+	if err != nil {
+		return plan, fmt.Errorf("failed to get a response from the model: %v", err)
+	}
+
+	var resp prioritizeResponse
+	if err := json.Unmarshal(content, &resp); err != nil {
+		return plan, fmt.Errorf("failed to parse model response: %v", err)
+	}
+
+	for _, estimate := range resp.Assignments {
+		a, ok := byID[estimate.ID]
+		// Ignore anything the model made up or repeated
+		if !ok {
+			continue
+		}
+		delete(byID, estimate.ID)
+
+		// Difficulty has to satisfy the CHECK on the assignments table
+		difficulty := min(max(estimate.Difficulty, 1), 10)
+		length := max(estimate.Length, 0)
+
+		// Estimates the user set themselves win over the model's
+		override, err := q.GetAssignmentOverride(ctx, a.ID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			fmt.Printf("Error fetching overrides for assignment %d: %v\n", a.ID, err)
+		}
+		if override.Difficulty.Valid {
+			difficulty = override.Difficulty.Int64
+		}
+		if override.Length.Valid {
+			length = override.Length.Int64
+		}
+
+		params := sqlite.UpdateAssignmentParams{
+			ID:         a.ID,
+			Name:       a.Name,
+			DueDate:    a.DueDate,
+			Difficulty: sql.NullInt64{Int64: difficulty, Valid: true},
+			Length:     sql.NullInt64{Int64: length, Valid: true},
+		}
+		if err := q.UpdateAssignment(ctx, params); err != nil {
+			fmt.Printf("Error saving estimates for assignment %d: %v\n", a.ID, err)
+		}
+
+		plan.Assignments = append(plan.Assignments, PrioritizedAssignment{
+			Rank:       len(plan.Assignments) + 1,
+			ID:         a.ID,
+			CourseID:   a.CourseID,
+			Name:       a.Name,
+			DueDate:    a.DueDate.Time.Format(time.RFC3339),
+			Difficulty: difficulty,
+			Length:     length,
+			Reason:     estimate.Reason,
+		})
+	}
+
+	return plan, nil
+}
+
+func toPromptAssignment(a sqlite.Assignment) Assignment {
+	assignment := Assignment{
+		ID:       strconv.FormatInt(a.ID, 10),
+		Name:     a.Name,
+		CourseID: strconv.FormatInt(a.CourseID, 10),
+		DueDate:  a.DueDate.Time.Format(time.RFC3339),
+	}
+	if a.PointsPossible.Valid {
+		assignment.PointsPossible = a.PointsPossible.Float64
+	}
+	if a.SubmissionTypes.Valid {
+		assignment.SubmissionTypes = a.SubmissionTypes.String
+	}
+	if a.Difficulty.Valid {
+		assignment.Difficulty = strconv.FormatInt(a.Difficulty.Int64, 10)
+	}
+	if a.Length.Valid {
+		assignment.Length = strconv.FormatInt(a.Length.Int64, 10)
+	}
+	return assignment
+}
+
+// Generates a new plan and caches it as the latest one
+func PostPrioritize(c *gin.Context, provider llm.Provider, q store.Store) {
+	limit := int64(defaultPrioritizeLimit)
+	if l := c.Query("limit"); l != "" {
+		parsed, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid limit",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	includeSubmitted := c.Query("include_submitted") == "true"
+	plan, err := PrioritizeAssignments(c.Request.Context(), provider, q, limit, includeSubmitted)
+	if err != nil {
+		fmt.Printf("Error prioritizing assignments: %v\n", err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Failed to prioritize assignments",
+		})
+		return
+	}
+
+	r := redis.GetInstance()
+	if err := r.SetWithExpirationContext(c.Request.Context(), latestPlanKey, plan, redis.KindPlan.TTL()); err != nil {
+		fmt.Printf("Error caching plan: %v\n", err)
+	}
+	// The new estimates were saved to the assignments
+	if len(plan.Assignments) > 0 {
+		if err := r.InvalidateContext(c.Request.Context(), redis.AllAssignmentsInvalidation()); err != nil {
+			fmt.Printf("Error invalidating cached assignments: %v\n", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// Returns the latest plan without calling the model again
+func GetPrioritize(c *gin.Context) {
+	r := redis.GetInstance()
+	val, err := r.GetContext(c.Request.Context(), latestPlanKey)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No plan has been generated yet",
+		})
+		return
+	}
+
+	var plan PriorityPlan
+	if err := json.Unmarshal([]byte(val), &plan); err != nil {
+		fmt.Printf("Error unmarshalling plan: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
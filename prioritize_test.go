@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/llm"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// Holds the model's answer for the fixtures below. Requests are matched byte for byte,
+// so record it again (LLM_PROVIDER=record) after changing them or the prompt.
+const testCassette = "testdata/llm_cassette.json"
+
+// Upcoming CPSC 449 assignments the cassette was recorded for
+func seedPrioritizeFixtures(t *testing.T, q store.Store) {
+	t.Helper()
+	ctx := context.Background()
+
+	if _, err := q.UpsertCourse(ctx, sqlite.UpsertCourseParams{ID: 449, Name: "CPSC 449"}); err != nil {
+		t.Fatal(err)
+	}
+	fixtures := []sqlite.UpsertAssignmentParams{
+		{
+			ID:              1001,
+			Name:            "Project 2: Canvas planner API",
+			DueDate:         sql.NullTime{Time: time.Date(2099, 5, 1, 7, 0, 0, 0, time.UTC), Valid: true},
+			PointsPossible:  sql.NullFloat64{Float64: 100, Valid: true},
+			SubmissionTypes: sql.NullString{String: "online_upload", Valid: true},
+		},
+		{
+			ID:              1002,
+			Name:            "Quiz 4: Caching",
+			DueDate:         sql.NullTime{Time: time.Date(2099, 4, 20, 7, 0, 0, 0, time.UTC), Valid: true},
+			PointsPossible:  sql.NullFloat64{Float64: 10, Valid: true},
+			SubmissionTypes: sql.NullString{String: "online_quiz", Valid: true},
+		},
+		{
+			ID:              1003,
+			Name:            "Reading response: Designing Data-Intensive Applications ch. 5",
+			DueDate:         sql.NullTime{Time: time.Date(2099, 4, 25, 7, 0, 0, 0, time.UTC), Valid: true},
+			Difficulty:      sql.NullInt64{Int64: 2, Valid: true},
+			PointsPossible:  sql.NullFloat64{Float64: 5, Valid: true},
+			SubmissionTypes: sql.NullString{String: "online_text_entry", Valid: true},
+		},
+	}
+	for _, a := range fixtures {
+		a.CourseID = 449
+		a.Published = true
+		if _, err := q.UpsertAssignment(ctx, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newPrioritizeRouter(provider llm.Provider, q store.Store) *gin.Engine {
+	r := gin.New()
+	r.POST("/prioritize", func(c *gin.Context) {
+		PostPrioritize(c, provider, q)
+	})
+	r.GET("/prioritize", GetPrioritize)
+	return r
+}
+
+func TestPostPrioritizeReplay(t *testing.T) {
+	q := newTestStore(t)
+	seedPrioritizeFixtures(t, q)
+	ctx := context.Background()
+
+	// The user's own estimate has to win over the model's
+	if err := q.UpsertAssignmentOverride(ctx, sqlite.UpsertAssignmentOverrideParams{
+		AssignmentID: 1001,
+		Length:       sql.NullInt64{Int64: 600, Valid: true},
+		CanvasName:   "Project 2: Canvas planner API",
+		UpdatedAt:    time.Now().UTC(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := llm.NewReplayer(testCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newPrioritizeRouter(provider, q)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/prioritize", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /prioritize = %d %s", w.Code, w.Body)
+	}
+	var plan PriorityPlan
+	if err := json.Unmarshal(w.Body.Bytes(), &plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan.Model != "gpt-4o-mini" {
+		t.Errorf("Model = %q, want gpt-4o-mini", plan.Model)
+	}
+
+	// The cassette ranks the quiz first and makes up an assignment 9999, which is dropped
+	want := []PrioritizedAssignment{
+		{Rank: 1, ID: 1002, Difficulty: 3, Length: 45},
+		{Rank: 2, ID: 1003, Difficulty: 2, Length: 60},
+		{Rank: 3, ID: 1001, Difficulty: 10, Length: 600},
+	}
+	if len(plan.Assignments) != len(want) {
+		t.Fatalf("got %d assignments, want %d: %+v", len(plan.Assignments), len(want), plan.Assignments)
+	}
+	for i, w := range want {
+		got := plan.Assignments[i]
+		if got.Rank != w.Rank || got.ID != w.ID || got.Difficulty != w.Difficulty || got.Length != w.Length {
+			t.Errorf("assignment %d = %+v, want rank %d id %d difficulty %d length %d", i, got, w.Rank, w.ID, w.Difficulty, w.Length)
+		}
+		if got.Reason == "" {
+			t.Errorf("assignment %d has no reason", got.ID)
+		}
+
+		// The estimates are saved with the assignment
+		a, err := q.GetAssignment(ctx, sqlite.GetAssignmentParams{ID: w.ID, CourseID: 449})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.Difficulty.Int64 != w.Difficulty || a.Length.Int64 != w.Length {
+			t.Errorf("assignment %d saved difficulty %v length %v, want %d and %d", w.ID, a.Difficulty, a.Length, w.Difficulty, w.Length)
+		}
+	}
+
+	// And the plan is cached for GET
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/prioritize", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /prioritize = %d %s", w.Code, w.Body)
+	}
+	var cached PriorityPlan
+	if err := json.Unmarshal(w.Body.Bytes(), &cached); err != nil {
+		t.Fatal(err)
+	}
+	if len(cached.Assignments) != len(want) || cached.Assignments[0].ID != 1002 {
+		t.Errorf("GET /prioritize returned %+v", cached.Assignments)
+	}
+}
+
+// Replaying never reaches the network, a prompt that wasn't recorded is an error
+func TestPostPrioritizeReplayUnrecorded(t *testing.T) {
+	q := newTestStore(t)
+	seedPrioritizeFixtures(t, q)
+
+	if _, err := q.UpsertAssignment(context.Background(), sqlite.UpsertAssignmentParams{
+		ID:        1004,
+		CourseID:  449,
+		Name:      "Final exam review",
+		DueDate:   sql.NullTime{Time: time.Date(2099, 5, 10, 7, 0, 0, 0, time.UTC), Valid: true},
+		Published: true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := llm.NewReplayer(testCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	newPrioritizeRouter(provider, q).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/prioritize", nil))
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("POST /prioritize = %d %s, want %d", w.Code, w.Body, http.StatusBadGateway)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/syllabus"
+)
+
+// Number of search results returned by default, and at most
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// Searches the names and descriptions of the assignments and the text of the uploaded
+// syllabi, best matches first. ?course_id= can be repeated to only search some courses.
+func GetSearch(c *gin.Context, q store.Store) {
+	text := c.Query("q")
+	match, ok := store.MatchQuery(text)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "q is required",
+		})
+		return
+	}
+
+	var courseIDs sql.NullString
+	if ids := c.QueryArray("course_id"); len(ids) > 0 {
+		parsed := make([]int64, len(ids))
+		for i, id := range ids {
+			var err error
+			if parsed[i], err = strconv.ParseInt(id, 10, 64); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": "Invalid course ID",
+				})
+				return
+			}
+		}
+		b, _ := json.Marshal(parsed)
+		courseIDs = sql.NullString{String: string(b), Valid: true}
+	}
+
+	limit := int64(defaultSearchLimit)
+	if l := c.Query("limit"); l != "" {
+		parsed, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || parsed <= 0 || parsed > maxSearchLimit {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid limit",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	results, err := q.Search(c.Request.Context(), sqlite.SearchParams{
+		Match:     match,
+		CourseIDs: courseIDs,
+		Limit:     limit,
+	})
+	if err != nil {
+		fmt.Printf("Error searching: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	if results == nil {
+		results = []sqlite.SearchRow{}
+	}
+	for i := range results {
+		results[i].Snippet = store.HighlightSnippet(results[i].Snippet)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"query":   text,
+		"results": results,
+	})
+}
+
+// Fills in the text of the descriptions saved before description_text existed (see
+// 0006_search_text), they can't be searched until then
+func fillDescriptionText(ctx context.Context, q store.Store) error {
+	missing, err := q.ListAssignmentsWithoutDescriptionText(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list assignments: %v", err)
+	}
+	for _, a := range missing {
+		if err := q.UpdateAssignmentDescriptionText(ctx, sqlite.UpdateAssignmentDescriptionTextParams{
+			ID:              a.ID,
+			DescriptionText: sql.NullString{String: syllabus.HTMLText(a.Description.String), Valid: true},
+		}); err != nil {
+			return fmt.Errorf("failed to save the text of assignment %d: %v", a.ID, err)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Printf("Indexed the descriptions of %d assignments for search\n", len(missing))
+	}
+	return nil
+}
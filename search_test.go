@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/syllabus"
+)
+
+type searchResponse struct {
+	Results []sqlite.SearchRow `json:"results"`
+}
+
+func search(t *testing.T, q store.Store, text string) []sqlite.SearchRow {
+	t.Helper()
+	r := gin.New()
+	r.GET("/search", func(c *gin.Context) {
+		GetSearch(c, q)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/search?q="+url.QueryEscape(text), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /search?q=%s = %d %s", text, w.Code, w.Body)
+	}
+	var resp searchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Results
+}
+
+func TestSearchIgnoresMarkup(t *testing.T) {
+	ctx := context.Background()
+	q := newTestStore(t)
+	if _, err := q.UpsertCourse(ctx, sqlite.UpsertCourseParams{ID: 449, Name: "CPSC 449"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saved the way the sync saves it
+	description := `<p><span class="x" style="font-weight: bold">Write the report</span> by Friday</p>`
+	if _, err := q.UpsertAssignment(ctx, sqlite.UpsertAssignmentParams{
+		ID:              1,
+		CourseID:        449,
+		Name:            "Lab 3",
+		Description:     sql.NullString{String: description, Valid: true},
+		DescriptionText: sql.NullString{String: syllabus.HTMLText(description), Valid: true},
+		Published:       true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// Text that talks about markup, which has to come back escaped
+	if _, err := q.UpsertSyllabus(ctx, sqlite.UpsertSyllabusParams{
+		CourseID:   449,
+		Filename:   "style-guide.txt",
+		Content:    `Never put <script> tags in a lab & call it done`,
+		UploadedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{"span", "class", "bold", "font"} {
+		if results := search(t, q, text); len(results) != 0 {
+			t.Errorf("q=%s matched the markup: %+v", text, results)
+		}
+	}
+
+	results := search(t, q, "report")
+	if len(results) != 1 || results[0].ID != 1 {
+		t.Fatalf("q=report = %+v, want assignment 1", results)
+	}
+	if want := "Write the <mark>report</mark> by Friday"; results[0].Snippet != want {
+		t.Errorf("snippet = %q, want %q", results[0].Snippet, want)
+	}
+
+	results = search(t, q, "script")
+	if len(results) != 1 || results[0].Kind != "syllabus" {
+		t.Fatalf("q=script = %+v, want the syllabus", results)
+	}
+	if want := "Never put &lt;<mark>script</mark>&gt; tags in a lab &amp; call it done"; results[0].Snippet != want {
+		t.Errorf("snippet = %q, want %q", results[0].Snippet, want)
+	}
+}
+
+// Descriptions saved before description_text existed are indexed once the service starts
+func TestFillDescriptionText(t *testing.T) {
+	ctx := context.Background()
+	q := newTestStore(t)
+	if _, err := q.UpsertCourse(ctx, sqlite.UpsertCourseParams{ID: 449, Name: "CPSC 449"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.UpsertAssignment(ctx, sqlite.UpsertAssignmentParams{
+		ID:          1,
+		CourseID:    449,
+		Name:        "Essay",
+		Description: sql.NullString{String: "<p>Compare <b>caching</b> strategies</p>", Valid: true},
+		Published:   true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if results := search(t, q, "strategies"); len(results) != 0 {
+		t.Fatalf("q=strategies matched before the text was filled in: %+v", results)
+	}
+
+	if err := fillDescriptionText(ctx, q); err != nil {
+		t.Fatal(err)
+	}
+	results := search(t, q, "strategies")
+	if len(results) != 1 || results[0].ID != 1 {
+		t.Fatalf("q=strategies = %+v, want assignment 1", results)
+	}
+	a, err := q.GetAssignment(ctx, sqlite.GetAssignmentParams{ID: 1, CourseID: 449})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.DescriptionText.String != "Compare caching strategies" {
+		t.Errorf("description_text = %q", a.DescriptionText.String)
+	}
+
+	// Nothing is left to fill in
+	missing, err := q.ListAssignmentsWithoutDescriptionText(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(missing) != 0 {
+		t.Errorf("still missing the text of %+v", missing)
+	}
+}
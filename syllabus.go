@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/syllabus"
+)
+
+var uploadedFilesDir = "./uploads"
+
+// Saves an uploaded syllabus and indexes its text for /search. The course it belongs to
+// is the course_id form field, the personal course by default. Files text can't be
+// extracted from (like PDFs) are still saved, they just can't be searched.
+func PostSyllabus(c *gin.Context, q store.Store) {
+	f, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No file uploaded",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	courseID := int64(personalCourseID)
+	if id := c.PostForm("course_id"); id != "" {
+		courseID, err = strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid course ID",
+			})
+			return
+		}
+	}
+	if _, err := q.GetCourse(ctx, courseID); errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Course not found",
+		})
+		return
+	} else if err != nil {
+		fmt.Printf("Error fetching course: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+
+	// Extract the text before saving anything, so a file that fails to extract isn't
+	// left behind in the uploads
+	file, err := f.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to read file",
+		})
+		return
+	}
+	defer file.Close()
+
+	text, err := syllabus.ExtractText(f.Filename, file)
+	indexed := !errors.Is(err, syllabus.ErrUnsupported)
+	if err != nil && indexed {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to extract text: %v", err),
+		})
+		return
+	}
+
+	// Each course has its own directory so courses with a file of the same name don't
+	// overwrite each other's
+	dir := filepath.Join(uploadedFilesDir, strconv.FormatInt(courseID, 10))
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		log.Println("Error creating directory:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+
+	if err := c.SaveUploadedFile(f, filepath.Join(dir, f.Filename)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save file",
+		})
+		return
+	}
+
+	if !indexed {
+		c.JSON(http.StatusOK, gin.H{
+			"filename":  f.Filename,
+			"message":   "File uploaded successfully, its text can't be searched",
+			"indexed":   false,
+			"course_id": courseID,
+		})
+		return
+	}
+
+	saved, err := q.UpsertSyllabus(ctx, sqlite.UpsertSyllabusParams{
+		CourseID:   courseID,
+		Filename:   f.Filename,
+		Content:    text,
+		UploadedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		fmt.Printf("Error saving syllabus: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"filename":    f.Filename,
+		"message":     "File uploaded successfully",
+		"indexed":     true,
+		"syllabus_id": saved.ID,
+		"course_id":   saved.CourseID,
+	})
+}
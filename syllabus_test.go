@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// Uploads a file to POST /syllabus for the course and returns the response
+func uploadSyllabus(t *testing.T, q store.Store, courseID, filename, content string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if err := mw.WriteField("course_id", courseID); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := gin.New()
+	r.POST("/syllabus", func(c *gin.Context) {
+		PostSyllabus(c, q)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/syllabus", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPostSyllabusPerCourse(t *testing.T) {
+	ctx := context.Background()
+	q := newTestStore(t)
+	dir := uploadedFilesDir
+	uploadedFilesDir = t.TempDir()
+	t.Cleanup(func() { uploadedFilesDir = dir })
+
+	for _, id := range []int64{449, 362} {
+		if _, err := q.UpsertCourse(ctx, sqlite.UpsertCourseParams{ID: id, Name: "Course"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Both courses have a syllabus.txt, neither overwrites the other
+	for _, upload := range []struct{ courseID, content string }{
+		{"449", "Web back-end engineering"},
+		{"362", "Software engineering"},
+	} {
+		if w := uploadSyllabus(t, q, upload.courseID, "syllabus.txt", upload.content); w.Code != http.StatusOK {
+			t.Fatalf("uploading the syllabus of %s = %d %s", upload.courseID, w.Code, w.Body)
+		}
+		b, err := os.ReadFile(filepath.Join(uploadedFilesDir, upload.courseID, "syllabus.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != upload.content {
+			t.Errorf("saved syllabus of %s = %q, want %q", upload.courseID, b, upload.content)
+		}
+	}
+	b, err := os.ReadFile(filepath.Join(uploadedFilesDir, "449", "syllabus.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "Web back-end engineering" {
+		t.Errorf("syllabus of 449 was overwritten with %q", b)
+	}
+
+	// Files text can't be extracted from are still saved
+	if w := uploadSyllabus(t, q, "449", "syllabus.pdf", "%PDF-1.7"); w.Code != http.StatusOK {
+		t.Fatalf("uploading a PDF = %d %s", w.Code, w.Body)
+	}
+	if _, err := os.Stat(filepath.Join(uploadedFilesDir, "449", "syllabus.pdf")); err != nil {
+		t.Error(err)
+	}
+
+	// A file that fails to extract isn't saved
+	if w := uploadSyllabus(t, q, "449", "latin1.txt", "caf\xe9"); w.Code != http.StatusBadRequest {
+		t.Errorf("uploading text that isn't UTF-8 = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if _, err := os.Stat(filepath.Join(uploadedFilesDir, "449", "latin1.txt")); !os.IsNotExist(err) {
+		t.Errorf("the file that failed to extract was saved: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/syncer"
+)
+
+// Runs a sync and responds with its report, or with the job to poll in async mode
+func PostSync(c *gin.Context, s *syncer.Syncer) {
+	req := syncer.Request{Source: syncer.SourceManual}
+	for param, dst := range map[string]*int{"term": &req.TermID, "course_id": &req.CourseID} {
+		val := c.Query(param)
+		if val == "" {
+			continue
+		}
+		id, err := strconv.Atoi(val)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid %s", param),
+			})
+			return
+		}
+		*dst = id
+	}
+
+	switch bucket := c.Query("bucket"); bucket {
+	case "", syncer.BucketUpcoming, syncer.BucketFuture:
+		req.Bucket = bucket
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid bucket, expected upcoming or future",
+		})
+		return
+	}
+	req.Full = c.Query("full") == "true"
+
+	if c.Query("async") == "true" {
+		job, err := s.StartJob(c.Request.Context(), req)
+		if errors.Is(err, syncer.ErrJobsUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Async syncs aren't available without a cache",
+			})
+			return
+		}
+		if err != nil {
+			fmt.Printf("Error starting sync job: %v\n", err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Internal server error",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	report, err := s.Run(c.Request.Context(), req)
+	if errors.Is(err, syncer.ErrAlreadyRunning) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "A sync is already running",
+		})
+		return
+	}
+	if errors.Is(err, syncer.ErrLockNotShared) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Syncs are paused until Redis is back",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error syncing: %v\n", err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  err.Error(),
+			"report": report,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Gets the status of an async sync, along with its report once it's done
+func GetSyncJob(c *gin.Context) {
+	job, ok := syncer.GetJob(c.Request.Context(), c.Param("jobID"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Sync job not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// Number of sync runs returned by default
+const defaultSyncRunsLimit = 20
+
+// Gets the most recent sync runs, newest first
+func GetSyncRuns(c *gin.Context, q store.Store) {
+	limit := int64(defaultSyncRunsLimit)
+	if l := c.Query("limit"); l != "" {
+		parsed, err := strconv.ParseInt(l, 10, 64)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid limit",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	runs, err := redis.GetOrLoad(c.Request.Context(), redis.GetInstance(), redis.KindSyncRuns,
+		redis.Key(redis.KindSyncRuns, limit), readCacheOptions,
+		func(ctx context.Context) ([]sqlite.SyncRun, error) {
+			return q.ListSyncRuns(ctx, limit)
+		})
+	if err != nil {
+		fmt.Printf("Error fetching sync runs from DB: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
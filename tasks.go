@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johncmanuel/cpsc449-project2/db/sqlite"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/redis"
+	"github.com/johncmanuel/cpsc449-project2/pkgs/store"
+)
+
+// Fields of a task the user can set. Tasks are assignments created by hand (study
+// sessions, exams announced in class, work from courses that aren't on Canvas), they
+// live in the assignments table with a negative ID and are never touched by the sync.
+var taskFields = []string{"name", "course_id", "due_date", "difficulty", "length", "description"}
+
+// Course the tasks without one go in, created by the 0003_assignment_source migration
+const personalCourseID = 0
+
+// Applies the patch to a task, checking the course it's moved to exists
+func taskParams(ctx context.Context, q store.Store, current sqlite.Assignment, patch fieldPatch) (sqlite.UpdateTaskIfVersionParams, error) {
+	params := sqlite.UpdateTaskIfVersionParams{
+		ID:          current.ID,
+		CourseID:    current.CourseID,
+		Name:        current.Name,
+		DueDate:     current.DueDate,
+		Difficulty:  current.Difficulty,
+		Length:      current.Length,
+		Description: current.Description,
+		Version:     current.Version,
+	}
+	if patch.set["name"] {
+		if !patch.values.Name.Valid {
+			return params, invalidFieldError{"name", "is required"}
+		}
+		params.Name = patch.values.Name.String
+	}
+	if patch.set["course_id"] {
+		params.CourseID = personalCourseID
+		if patch.values.CourseID.Valid {
+			params.CourseID = patch.values.CourseID.Int64
+		}
+	}
+	if patch.set["due_date"] {
+		params.DueDate = patch.values.DueDate
+	}
+	if patch.set["difficulty"] {
+		params.Difficulty = patch.values.Difficulty
+	}
+	if patch.set["length"] {
+		params.Length = patch.values.Length
+	}
+	if patch.set["description"] {
+		params.Description = patch.values.Description
+	}
+
+	if params.CourseID != current.CourseID {
+		if _, err := q.GetCourse(ctx, params.CourseID); errors.Is(err, sql.ErrNoRows) {
+			return params, invalidFieldError{"course_id", "doesn't match a course"}
+		} else if err != nil {
+			return params, fmt.Errorf("failed to fetch course: %v", err)
+		}
+	}
+	return params, nil
+}
+
+// Saves the patched task. Returns sql.ErrNoRows when it was changed since current was
+// read.
+func writeTask(ctx context.Context, q store.Store, current sqlite.Assignment, patch fieldPatch) (sqlite.Assignment, error) {
+	params, err := taskParams(ctx, q, current, patch)
+	if err != nil {
+		return current, err
+	}
+	return q.UpdateTaskIfVersion(ctx, params)
+}
+
+func parseTaskID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid task ID",
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// Creates a task, only its name is required
+func PostTask(c *gin.Context, q store.Store) {
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read body",
+		})
+		return
+	}
+	patch, err := parseFields(body, taskFields, true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Start from an empty task in the personal course
+	ctx := c.Request.Context()
+	params, err := taskParams(ctx, q, sqlite.Assignment{CourseID: personalCourseID}, patch)
+	var invalid invalidFieldError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": invalid.Error(),
+		})
+		return
+	}
+	var task sqlite.Assignment
+	if err == nil {
+		task, err = q.CreateTask(ctx, sqlite.CreateTaskParams{
+			CourseID:    params.CourseID,
+			Name:        params.Name,
+			DueDate:     params.DueDate,
+			Difficulty:  params.Difficulty,
+			Length:      params.Length,
+			Description: params.Description,
+			Source:      store.SourceManual,
+		})
+	}
+	if err != nil {
+		fmt.Printf("Error creating task: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+
+	cacheAssignment(ctx, task, task.CourseID)
+	c.Header("Location", fmt.Sprintf("/tasks/%d", task.ID))
+	c.Header("ETag", assignmentETag(task))
+	c.JSON(http.StatusCreated, task)
+}
+
+// Gets every task, ordered by due date. They also show up with the assignments.
+func GetTasks(c *gin.Context, q store.Store) {
+	tasks, err := redis.GetOrLoad(c.Request.Context(), redis.GetInstance(), redis.KindAssignmentList,
+		redis.Key(redis.KindAssignmentList, "tasks"), readCacheOptions, q.ListTasks)
+	if err != nil {
+		fmt.Printf("Error fetching tasks from DB: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, tasks)
+}
+
+// Gets an individual task. It's looked up by ID alone, so it comes from the DB rather
+// than the assignment's cache key; /:courseID/assignments/:assignmentID works too.
+func GetTask(c *gin.Context, q store.Store) {
+	id, ok := parseTaskID(c)
+	if !ok {
+		return
+	}
+
+	task, err := q.GetTask(c.Request.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Task not found",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error fetching task: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+	c.Header("ETag", assignmentETag(task))
+	c.JSON(http.StatusOK, task)
+}
+
+// Replaces a task, the optional fields left out of the body are cleared
+func UpdateTask(c *gin.Context, q store.Store) {
+	saveTask(c, q, true)
+}
+
+// Changes some of a task's fields with a JSON merge patch
+func PatchTask(c *gin.Context, q store.Store) {
+	saveTask(c, q, false)
+}
+
+func saveTask(c *gin.Context, q store.Store, replace bool) {
+	id, ok := parseTaskID(c)
+	if !ok {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Failed to read body",
+		})
+		return
+	}
+	patch, err := parseFields(body, taskFields, replace)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	saveAssignment(c,
+		func(ctx context.Context) (sqlite.Assignment, error) {
+			return q.GetTask(ctx, id)
+		},
+		func(ctx context.Context, current sqlite.Assignment) (sqlite.Assignment, error) {
+			return writeTask(ctx, q, current, patch)
+		})
+}
+
+func DeleteTask(c *gin.Context, q store.Store) {
+	id, ok := parseTaskID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	// The course is needed to know what to evict from the cache
+	task, err := q.GetTask(ctx, id)
+	var deleted int64
+	if err == nil {
+		deleted, err = q.DeleteTask(ctx, id)
+	}
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && deleted == 0) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Task not found",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error deleting task: %v\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Internal server error",
+		})
+		return
+	}
+
+	if err := redis.GetInstance().InvalidateContext(ctx, redis.AssignmentInvalidation(task.CourseID, task.ID)); err != nil {
+		fmt.Printf("Error invalidating cached task: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Task deleted",
+	})
+}